@@ -0,0 +1,315 @@
+package gorest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApplyListQuery filters and sorts the items in memory according to the list query.
+// It is meant for tests and small collections, where the items are already loaded.
+//
+// The query fields are matched with the struct fields by their json name, or by their name case-insensitively.
+// Nested struct fields are referenced with a dot: owner.name
+// String, number, bool and time.Time fields are supported, where time values are RFC 3339 timestamps or dates.
+// A field behind a nil pointer is null: it only equals null in a filter, and it is sorted before the other values.
+//
+// The returned error is a *QueryError when a value can't be compared with its field.
+func ApplyListQuery[T any](items []T, q ListQuery) ([]T, error) {
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		ok, err := evalFilter(reflect.ValueOf(item), q.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, item)
+		}
+	}
+
+	var err error
+	sort.SliceStable(result, func(i, j int) bool {
+		for _, sf := range q.Sort {
+			a, aok := lookupQueryField(reflect.ValueOf(result[i]), sf.Field)
+			b, bok := lookupQueryField(reflect.ValueOf(result[j]), sf.Field)
+			if !aok || !bok {
+				if err == nil {
+					err = &QueryError{Param: sortParam, Offset: sf.Offset, Msg: fmt.Sprintf(`unknown field %q`, sf.Field)}
+				}
+				return false
+			}
+			c, cerr := compareFieldValues(a, b)
+			if cerr != nil {
+				if err == nil {
+					err = &QueryError{Param: sortParam, Offset: sf.Offset, Msg: cerr.Error()}
+				}
+				return false
+			}
+			if c == 0 {
+				continue
+			}
+			return (c < 0) != sf.Desc
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func evalFilter(item reflect.Value, expr FilterExpr) (bool, error) {
+	switch expr := expr.(type) {
+	case nil:
+		return true, nil
+	case *FilterNot:
+		ok, err := evalFilter(item, expr.Expr)
+		return !ok, err
+	case *FilterLogical:
+		left, err := evalFilter(item, expr.Left)
+		if err != nil {
+			return false, err
+		}
+		if left == (expr.Op == `or`) { // short circuit
+			return left, nil
+		}
+		return evalFilter(item, expr.Right)
+	case *FilterComparison:
+		field, ok := lookupQueryField(item, expr.Field)
+		if !ok {
+			return false, &QueryError{Param: filterParam, Offset: expr.Offset, Msg: fmt.Sprintf(`unknown field %q`, expr.Field)}
+		}
+		ok, err := compareWithFilterValue(field, expr.Op, expr.Value)
+		if err != nil {
+			return false, &QueryError{Param: filterParam, Offset: expr.Value.Offset, Msg: err.Error()}
+		}
+		return ok, nil
+	default:
+		return false, fmt.Errorf(`gorest: unknown filter expression %T`, expr)
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func compareWithFilterValue(field reflect.Value, op FilterOp, value FilterValue) (bool, error) {
+	if !field.IsValid() {
+		return compareNilWithFilterValue(op, value), nil
+	}
+	for field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			return compareNilWithFilterValue(op, value), nil
+		}
+		field = field.Elem()
+	}
+	if value.Kind == FilterNull {
+		return op == FilterNe, nil
+	}
+
+	if op == FilterContains {
+		if field.Kind() != reflect.String {
+			return false, fmt.Errorf(`contains can only be used on text fields`)
+		}
+		return strings.Contains(field.String(), value.Raw), nil
+	}
+
+	operand, err := parseFilterOperand(field.Type(), value.Raw)
+	if err != nil {
+		return false, err
+	}
+	c, err := compareFieldValues(field, operand)
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case FilterEq:
+		return c == 0, nil
+	case FilterNe:
+		return c != 0, nil
+	case FilterGt:
+		return 0 < c, nil
+	case FilterGe:
+		return 0 <= c, nil
+	case FilterLt:
+		return c < 0, nil
+	case FilterLe:
+		return c <= 0, nil
+	default:
+		return false, fmt.Errorf(`unknown operator %q`, op)
+	}
+}
+
+// compareNilWithFilterValue compares a null field, which only equals null.
+func compareNilWithFilterValue(op FilterOp, value FilterValue) bool {
+	if value.Kind == FilterNull {
+		return op == FilterEq
+	}
+	return op == FilterNe
+}
+
+// parseFilterOperand parses the raw filter value as a value of the field type.
+func parseFilterOperand(typ reflect.Type, raw string) (reflect.Value, error) {
+	if typ == timeType {
+		for _, layout := range []string{time.RFC3339Nano, `2006-01-02`} {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return reflect.ValueOf(t), nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf(`%q is not a time`, raw)
+	}
+
+	v := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf(`%q is not a boolean`, raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf(`%q is not an integer`, raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf(`%q is not an unsigned integer`, raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, typ.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf(`%q is not a number`, raw)
+		}
+		v.SetFloat(n)
+	default:
+		return reflect.Value{}, fmt.Errorf(`%s fields can't be filtered`, typ)
+	}
+	return v, nil
+}
+
+// compareFieldValues compares two values of the same type, and returns -1, 0 or 1.
+// Nil pointers and invalid values are ordered before every other value.
+func compareFieldValues(a, b reflect.Value) (int, error) {
+	if !a.IsValid() || !b.IsValid() {
+		return compareBools(a.IsValid(), b.IsValid()), nil
+	}
+	for a.Kind() == reflect.Ptr || a.Kind() == reflect.Interface {
+		if a.IsNil() || b.IsNil() {
+			return compareBools(!a.IsNil(), !b.IsNil()), nil
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+
+	if a.Type() == timeType {
+		ta, tb := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1, nil
+		case ta.After(tb):
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), nil
+	case reflect.Bool:
+		return compareBools(a.Bool(), b.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int(), b.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(a.Uint(), b.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float(), b.Float()), nil
+	default:
+		return 0, fmt.Errorf(`%s fields can't be compared`, a.Type())
+	}
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case b < a:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// lookupQueryField finds the struct field of the item that a query field path references.
+// When a pointer on the path is nil, the path is resolved by the types,
+// and the field is present with an invalid value, which stands for null.
+// The path behind a nil interface can't be resolved, so it is taken as present as well.
+func lookupQueryField(item reflect.Value, path string) (reflect.Value, bool) {
+	if !item.IsValid() {
+		return reflect.Value{}, false
+	}
+	v, t := item, item.Type()
+	for _, name := range strings.Split(path, `.`) {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+			if v.IsValid() && !v.IsNil() {
+				v = v.Elem()
+				t = v.Type()
+				continue
+			}
+			if t.Kind() == reflect.Interface {
+				return reflect.Value{}, true
+			}
+			v, t = reflect.Value{}, t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		i, ok := structFieldByQueryName(t, name)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		t = t.Field(i).Type
+		if v.IsValid() {
+			v = v.Field(i)
+		}
+	}
+	return v, true
+}
+
+func structFieldByQueryName(t reflect.Type, name string) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != `` { // unexported
+			continue
+		}
+		if tag := strings.Split(sf.Tag.Get(`json`), `,`)[0]; tag != `` && tag != `-` {
+			if tag == name {
+				return i, true
+			}
+			continue
+		}
+		if strings.EqualFold(sf.Name, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package gorest
+
+import (
+	"net/http"
+)
+
+// Authorization is the decision of an Authorizer.
+type Authorization int
+
+const (
+	// Deny rejects the request with 403 Forbidden.
+	// It is the zero value, so an unset decision never grants access.
+	Deny Authorization = iota
+	// Allow lets the request to be served by the operation.
+	Allow
+	// Hide rejects the request with 404 Not Found, so the requester can't tell the resource exists.
+	Hide
+)
+
+func (a Authorization) String() string {
+	switch a {
+	case Allow:
+		return `Allow`
+	case Hide:
+		return `Hide`
+	default:
+		return `Deny`
+	}
+}
+
+// Authorizer decides whether a request can be served by an operation.
+// It is called after ContextWithResource, so the request context has the loaded resource,
+// and before the middlewares registered with UseFor.
+// Returning an error replies the request as an error of the Handler.
+//
+// A controller can implement it, or it can be set on the Handler.
+type Authorizer interface {
+	Authorize(r *http.Request, op Operation) (Authorization, error)
+}
+
+// AuthorizerFunc is an adapter to use an ordinary function as an Authorizer.
+type AuthorizerFunc func(r *http.Request, op Operation) (Authorization, error)
+
+func (fn AuthorizerFunc) Authorize(r *http.Request, op Operation) (Authorization, error) {
+	return fn(r, op)
+}
+
+// authorize reports whether the request can be served by the operation.
+// When it can't, the rejection is already replied.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, op Operation) bool {
+	if h.Authorizer == nil {
+		return true
+	}
+
+	authz, err := h.Authorizer.Authorize(r, op)
+	if err != nil {
+		h.handleError(w, r, err)
+		return false
+	}
+
+	switch authz {
+	case Allow:
+		return true
+	case Hide:
+		h.notFound(w, r)
+		return false
+	default:
+		h.handleError(w, r, ErrForbidden)
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+type AuthorizerController struct {
+	StubController
+	gorest.AuthorizerFunc
+}
+
+func TestHandler_Authorizer(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`decision`, func(t *testcase.T) interface{} { return gorest.Allow })
+	s.Let(`error`, func(t *testcase.T) interface{} { return nil })
+	s.Let(`calls`, func(t *testcase.T) interface{} { return &[]string{} })
+	var calls = func(t *testcase.T) []string { return *t.I(`calls`).(*[]string) }
+
+	s.Let(`controller`, func(t *testcase.T) interface{} {
+		return AuthorizerController{
+			StubController: StubController{
+				ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+					return context.WithValue(ctx, `id`, id), true, nil
+				},
+				ShowFunc: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) },
+			},
+			AuthorizerFunc: func(r *http.Request, op gorest.Operation) (gorest.Authorization, error) {
+				ptr := t.I(`calls`).(*[]string)
+				id, _ := r.Context().Value(`id`).(string)
+				*ptr = append(*ptr, op.String()+`:`+id)
+				err, _ := t.I(`error`).(error)
+				return t.I(`decision`).(gorest.Authorization), err
+			},
+		}
+	})
+	s.Let(`handler`, func(t *testcase.T) interface{} { return gorest.NewHandler(t.I(`controller`)) })
+	var handler = func(t *testcase.T) *gorest.Handler { return t.I(`handler`).(*gorest.Handler) }
+	var serve = func(t *testcase.T, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(t).ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	s.When(`the authorizer allows the operation`, func(s *testcase.Spec) {
+		s.Let(`decision`, func(t *testcase.T) interface{} { return gorest.Allow })
+
+		s.Then(`the operation is served`, func(t *testcase.T) {
+			require.Equal(t, http.StatusTeapot, serve(t, http.MethodGet, `/42`).Code)
+		})
+
+		s.Then(`the authorizer receives the operation and the loaded resource`, func(t *testcase.T) {
+			serve(t, http.MethodGet, `/`)
+			serve(t, http.MethodPost, `/`)
+			serve(t, http.MethodGet, `/42`)
+			serve(t, http.MethodDelete, `/42`)
+			require.Equal(t, []string{`List:`, `Create:`, `Show:42`, `Delete:42`}, calls(t))
+		})
+
+		s.Then(`custom operations are authorized as well`, func(t *testcase.T) {
+			handler(t).Handle(`/custom`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			serve(t, http.MethodPost, `/42/custom`)
+			require.Equal(t, []string{`Custom:42`}, calls(t))
+		})
+
+		s.Then(`it is consulted before the UseFor middlewares`, func(t *testcase.T) {
+			handler(t).UseFor(gorest.OpShow, func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					require.Len(t, calls(t), 1)
+					next.ServeHTTP(w, r)
+				})
+			})
+			require.Equal(t, http.StatusTeapot, serve(t, http.MethodGet, `/42`).Code)
+		})
+	})
+
+	s.When(`the authorizer denies the operation`, func(s *testcase.Spec) {
+		s.Let(`decision`, func(t *testcase.T) interface{} { return gorest.Deny })
+
+		s.Then(`it is replied with forbidden`, func(t *testcase.T) {
+			require.Equal(t, http.StatusForbidden, serve(t, http.MethodGet, `/42`).Code)
+			require.Equal(t, http.StatusForbidden, serve(t, http.MethodGet, `/`).Code)
+		})
+	})
+
+	s.When(`the authorizer hides the operation`, func(s *testcase.Spec) {
+		s.Let(`decision`, func(t *testcase.T) interface{} { return gorest.Hide })
+
+		s.Then(`it is replied with not found`, func(t *testcase.T) {
+			handler(t).NotFound = NewTestControllerMockHandler(nil, http.StatusNotFound, `custom-not-found`)
+			resp := serve(t, http.MethodGet, `/42`)
+			require.Equal(t, http.StatusNotFound, resp.Code)
+			require.Contains(t, resp.Body.String(), `custom-not-found`)
+		})
+	})
+
+	s.When(`the authorizer fails`, func(s *testcase.Spec) {
+		s.Let(`decision`, func(t *testcase.T) interface{} { return gorest.Allow })
+		s.Let(`error`, func(t *testcase.T) interface{} { return errors.New(`boom`) })
+
+		s.Then(`the error is replied`, func(t *testcase.T) {
+			require.Equal(t, http.StatusInternalServerError, serve(t, http.MethodGet, `/42`).Code)
+		})
+	})
+
+	s.When(`the authorizer is set on the Handler`, func(s *testcase.Spec) {
+		s.Let(`handler`, func(t *testcase.T) interface{} {
+			h := gorest.NewHandler(t.I(`controller`).(AuthorizerController).StubController)
+			h.Authorizer = gorest.AuthorizerFunc(func(r *http.Request, op gorest.Operation) (gorest.Authorization, error) {
+				if op == gorest.OpShow {
+					return gorest.Allow, nil
+				}
+				return gorest.Deny, nil
+			})
+			return h
+		})
+
+		s.Then(`it is used`, func(t *testcase.T) {
+			require.Equal(t, http.StatusTeapot, serve(t, http.MethodGet, `/42`).Code)
+			require.Equal(t, http.StatusForbidden, serve(t, http.MethodDelete, `/42`).Code)
+		})
+	})
+}
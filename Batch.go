@@ -0,0 +1,270 @@
+package gorest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// BatchMode tells how the items of a batch are executed.
+type BatchMode int
+
+const (
+	// BatchOrdered executes the items one after the other, in the order of the batch.
+	BatchOrdered BatchMode = iota
+	// BatchParallel executes the items concurrently.
+	BatchParallel
+)
+
+// BatchRequest is an item of a batch request.
+type BatchRequest struct {
+	Method string `json:"method"`
+	// Path is the path of the item, with an optional query, relative to the Handler that serves the batch.
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the JSON request body of the item.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResponse is the response of a batch request item.
+type BatchResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the response body of the item.
+	// A JSON response body is embedded as is, any other body is embedded as a JSON string.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchHandler executes the items of a batch request with a http.Handler, and replies with their responses.
+// The batch request body is a JSON array of BatchRequest, and the response body is a JSON array of BatchResponse,
+// where each response is at the position of its request.
+//
+// The items are served with the headers of the batch request, overridden by the headers of the item,
+// and their context is derived from the batch request,
+// so when the Handler is a gorest Handler, ContextWithResource and the Authorizer apply to each item as usual.
+//
+// BatchHandler can be mounted as any http.Handler, or it can be enabled with the Batch field of a Handler,
+// which serves it as the batch collection custom method: POST /:batch
+type BatchHandler struct {
+	// Handler serves the items of the batch.
+	// When the BatchHandler is set as the Batch of a Handler, it defaults to that Handler.
+	Handler http.Handler
+	// Mode tells how the items are executed. It is ignored when the batch has a Transaction.
+	Mode BatchMode
+	// MaxItems is the maximum number of items in a batch. When it is zero, 100 items are accepted.
+	MaxItems int
+	// Transaction makes the batch all-or-nothing.
+	// It receives a function that executes the items in order within the context of the transaction,
+	// and returns an error when an item fails, in which case the transaction is expected to roll back.
+	// After a failed item, the rest of the items are not executed,
+	// and every item other than the failed one is replied with 424 Failed Dependency.
+	Transaction func(ctx context.Context, run func(ctx context.Context) error) error
+}
+
+const batchVerb = `batch`
+
+// serveBatch serves the batch endpoint of the Handler.
+func (h *Handler) serveBatch(w http.ResponseWriter, r *http.Request) {
+	b := *h.Batch
+	if b.Handler == nil {
+		b.Handler = h
+	}
+	b.ServeHTTP(w, r)
+}
+
+type ctxKeyBatch struct{}
+
+// errBatchItemFailed aborts the transaction of an all-or-nothing batch.
+var errBatchItemFailed = errors.New(`gorest: batch item failed`)
+
+func (b BatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set(`Allow`, http.MethodPost)
+		httpError(w, r, http.StatusMethodNotAllowed, ``)
+		return
+	}
+	if r.Context().Value(ctxKeyBatch{}) != nil {
+		WriteError(w, r, fmt.Errorf(`%w: batches can't be nested`, ErrBadRequest))
+		return
+	}
+	if !isJSONMediaType(r.Header.Get(`Content-Type`)) {
+		WriteError(w, r, ErrUnsupportedMediaType)
+		return
+	}
+
+	var items []BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		WriteError(w, r, fmt.Errorf(`%w: %v`, ErrBadRequest, err))
+		return
+	}
+	if len(items) == 0 || b.maxItems() < len(items) {
+		WriteError(w, r, &ValidationError{Fields: map[string]string{
+			`items`: fmt.Sprintf(`a batch must have between 1 and %d items`, b.maxItems()),
+		}})
+		return
+	}
+	for i, item := range items {
+		if item.Method == `` || !strings.HasPrefix(item.Path, `/`) {
+			WriteError(w, r, &ValidationError{Fields: map[string]string{
+				fmt.Sprintf(`%d`, i): `an item must have a method and an absolute path`,
+			}})
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), ctxKeyBatch{}, true)
+	responses, err := b.execute(ctx, r, items)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(responses)
+}
+
+func (b BatchHandler) execute(ctx context.Context, r *http.Request, items []BatchRequest) ([]BatchResponse, error) {
+	responses := make([]BatchResponse, len(items))
+
+	switch {
+	case b.Transaction != nil:
+		failed := -1
+		err := b.Transaction(ctx, func(ctx context.Context) error {
+			for i, item := range items {
+				responses[i] = b.serveItem(ctx, r, item)
+				if 400 <= responses[i].Status {
+					failed = i
+					return errBatchItemFailed
+				}
+			}
+			return nil
+		})
+		if failed < 0 && err != nil {
+			return nil, err
+		}
+		if 0 <= failed {
+			for i := range responses {
+				if i != failed {
+					responses[i] = BatchResponse{Status: http.StatusFailedDependency}
+				}
+			}
+		}
+
+	case b.Mode == BatchParallel:
+		var wg sync.WaitGroup
+		for i, item := range items {
+			wg.Add(1)
+			go func(i int, item BatchRequest) {
+				defer wg.Done()
+				responses[i] = b.serveItem(ctx, r, item)
+			}(i, item)
+		}
+		wg.Wait()
+
+	default:
+		for i, item := range items {
+			responses[i] = b.serveItem(ctx, r, item)
+		}
+	}
+
+	return responses, nil
+}
+
+// serveItem serves a batch item as a request derived from the batch request.
+// A panic of the item is replied as a 500 response of the item,
+// so it can't abort the rest of the batch, or crash the process in parallel mode.
+func (b BatchHandler) serveItem(ctx context.Context, batch *http.Request, item BatchRequest) (resp BatchResponse) {
+	defer func() {
+		if recover() != nil {
+			resp = BatchResponse{Status: http.StatusInternalServerError}
+		}
+	}()
+
+	u, err := url.Parse(item.Path)
+	if err != nil {
+		return BatchResponse{Status: http.StatusBadRequest}
+	}
+
+	r := batch.Clone(ctx)
+	r.Method = strings.ToUpper(item.Method)
+	r.URL = u
+	r.RequestURI = u.RequestURI()
+	for _, header := range []string{`Content-Type`, `Content-Length`, `Idempotency-Key`} {
+		r.Header.Del(header)
+	}
+	r.Body = http.NoBody
+	r.ContentLength = 0
+	if 0 < len(item.Body) {
+		r.Body = io.NopCloser(bytes.NewReader(item.Body))
+		r.ContentLength = int64(len(item.Body))
+		r.Header.Set(`Content-Type`, `application/json`)
+	}
+	for name, value := range item.Headers {
+		r.Header.Set(name, value)
+	}
+
+	w := &batchResponseWriter{header: make(http.Header)}
+	b.Handler.ServeHTTP(w, r)
+	return w.response()
+}
+
+func (b BatchHandler) maxItems() int {
+	if b.MaxItems <= 0 {
+		return 100
+	}
+	return b.MaxItems
+}
+
+// batchResponseWriter records the response of a batch item.
+type batchResponseWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header { return w.header }
+
+func (w *batchResponseWriter) WriteHeader(code int) {
+	if w.code == 0 && http.StatusOK <= code {
+		w.code = code
+	}
+}
+
+func (w *batchResponseWriter) Write(bs []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(bs)
+}
+
+func (w *batchResponseWriter) response() BatchResponse {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	resp := BatchResponse{Status: w.code}
+	for name := range w.header {
+		if resp.Headers == nil {
+			resp.Headers = make(map[string]string)
+		}
+		resp.Headers[name] = w.header.Get(name)
+	}
+
+	body := bytes.TrimSpace(w.body.Bytes())
+	switch {
+	case len(body) == 0:
+	case isJSONMediaType(w.header.Get(`Content-Type`)) && json.Valid(body):
+		resp.Body = body
+	default:
+		resp.Body, _ = json.Marshal(string(body))
+	}
+	return resp
+}
@@ -0,0 +1,193 @@
+package gorest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestHandler_Batch(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`batch`, func(t *testcase.T) interface{} { return &gorest.BatchHandler{} })
+	s.Let(`served`, func(t *testcase.T) interface{} { return &batchLog{} })
+	var served = func(t *testcase.T) []string { return t.I(`served`).(*batchLog).entries() }
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		log := t.I(`served`).(*batchLog)
+		h := gorest.NewHandler(StubController{
+			CreateFunc: func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				log.add(`create ` + string(body))
+				w.Header().Set(`Content-Type`, `application/json`)
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write(body)
+			},
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				return context.WithValue(ctx, `id`, id), id != `missing`, nil
+			},
+			ShowFunc: func(w http.ResponseWriter, r *http.Request) {
+				log.add(`show ` + r.Context().Value(`id`).(string))
+				_, _ = io.WriteString(w, `id `+r.Context().Value(`id`).(string)+` for `+r.Header.Get(`X-User`))
+			},
+		})
+		h.Batch = t.I(`batch`).(*gorest.BatchHandler)
+		return h
+	})
+	var serve = func(t *testcase.T, method, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, `/:batch`, strings.NewReader(body))
+		r.Header.Set(`Content-Type`, `application/json`)
+		r.Header.Set(`X-User`, `jane`)
+		w := httptest.NewRecorder()
+		t.I(`handler`).(*gorest.Handler).ServeHTTP(w, r)
+		return w
+	}
+	var responses = func(t *testcase.T, resp *httptest.ResponseRecorder) []gorest.BatchResponse {
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		var rs []gorest.BatchResponse
+		require.Nil(t, json.Unmarshal(resp.Body.Bytes(), &rs))
+		return rs
+	}
+	var statuses = func(rs []gorest.BatchResponse) []int {
+		var codes []int
+		for _, r := range rs {
+			codes = append(codes, r.Status)
+		}
+		return codes
+	}
+
+	const items = `[
+		{"method": "POST", "path": "/", "body": {"name": "a"}},
+		{"method": "GET", "path": "/42"},
+		{"method": "GET", "path": "/missing"},
+		{"method": "GET", "path": "/7", "headers": {"X-User": "joe"}}
+	]`
+
+	s.Test(`items are served in order through the Handler`, func(t *testcase.T) {
+		rs := responses(t, serve(t, http.MethodPost, items))
+		require.Equal(t, []int{http.StatusCreated, http.StatusOK, http.StatusNotFound, http.StatusOK}, statuses(rs))
+		require.JSONEq(t, `{"name":"a"}`, string(rs[0].Body))
+		require.Equal(t, `application/json`, rs[0].Headers[`Content-Type`])
+		require.Equal(t, `"id 42 for jane"`, string(rs[1].Body))
+		require.Equal(t, `"id 7 for joe"`, string(rs[3].Body))
+		require.Equal(t, []string{`create {"name": "a"}`, `show 42`, `show 7`}, served(t))
+	})
+
+	s.When(`the mode is parallel`, func(s *testcase.Spec) {
+		s.Let(`batch`, func(t *testcase.T) interface{} { return &gorest.BatchHandler{Mode: gorest.BatchParallel} })
+
+		s.Then(`every item is served at its position`, func(t *testcase.T) {
+			rs := responses(t, serve(t, http.MethodPost, items))
+			require.Equal(t, []int{http.StatusCreated, http.StatusOK, http.StatusNotFound, http.StatusOK}, statuses(rs))
+			require.ElementsMatch(t, []string{`create {"name": "a"}`, `show 42`, `show 7`}, served(t))
+		})
+	})
+
+	s.When(`the batch is all-or-nothing`, func(s *testcase.Spec) {
+		s.Let(`tx`, func(t *testcase.T) interface{} { return new(error) })
+		var txErr = func(t *testcase.T) error { return *t.I(`tx`).(*error) }
+		s.Let(`batch`, func(t *testcase.T) interface{} {
+			return &gorest.BatchHandler{Transaction: func(ctx context.Context, run func(context.Context) error) error {
+				err := run(ctx)
+				*t.I(`tx`).(*error) = err
+				return err
+			}}
+		})
+
+		s.Then(`a failed item rolls back the batch`, func(t *testcase.T) {
+			rs := responses(t, serve(t, http.MethodPost, items))
+			require.Equal(t, []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound, http.StatusFailedDependency}, statuses(rs))
+			require.Equal(t, []string{`create {"name": "a"}`, `show 42`}, served(t))
+			require.Error(t, txErr(t))
+		})
+
+		s.Then(`successful items are committed`, func(t *testcase.T) {
+			rs := responses(t, serve(t, http.MethodPost, `[{"method": "GET", "path": "/1"}, {"method": "GET", "path": "/2"}]`))
+			require.Equal(t, []int{http.StatusOK, http.StatusOK}, statuses(rs))
+			require.Nil(t, txErr(t))
+		})
+
+		s.And(`the transaction fails on its own`, func(s *testcase.Spec) {
+			s.Let(`batch`, func(t *testcase.T) interface{} {
+				return &gorest.BatchHandler{Transaction: func(ctx context.Context, run func(context.Context) error) error {
+					_ = run(ctx)
+					return errors.New(`commit failed`)
+				}}
+			})
+
+			s.Then(`the batch fails`, func(t *testcase.T) {
+				require.Equal(t, http.StatusInternalServerError, serve(t, http.MethodPost, `[{"method": "GET", "path": "/1"}]`).Code)
+			})
+		})
+	})
+
+	for name, mode := range map[string]gorest.BatchMode{`ordered`: gorest.BatchOrdered, `parallel`: gorest.BatchParallel} {
+		mode := mode
+
+		s.When(`an item panics in `+name+` mode`, func(s *testcase.Spec) {
+			s.Let(`batch`, func(t *testcase.T) interface{} { return &gorest.BatchHandler{Mode: mode} })
+			s.Before(func(t *testcase.T) {
+				t.I(`handler`).(*gorest.Handler).HandleCustom(gorest.CollectionScope, `explode`, http.MethodPost, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusCreated)
+					panic(`boom`)
+				}))
+			})
+
+			s.Then(`the item is replied with 500, and the rest of the batch is served`, func(t *testcase.T) {
+				rs := responses(t, serve(t, http.MethodPost, `[
+					{"method": "POST", "path": "/:explode"},
+					{"method": "GET", "path": "/42"}
+				]`))
+				require.Equal(t, []int{http.StatusInternalServerError, http.StatusOK}, statuses(rs))
+			})
+		})
+	}
+
+	s.Test(`invalid batches are rejected`, func(t *testcase.T) {
+		require.Equal(t, http.StatusMethodNotAllowed, serve(t, http.MethodGet, ``).Code)
+		require.Equal(t, http.StatusBadRequest, serve(t, http.MethodPost, `{}`).Code)
+		require.Equal(t, http.StatusUnprocessableEntity, serve(t, http.MethodPost, `[]`).Code)
+		require.Equal(t, http.StatusUnprocessableEntity, serve(t, http.MethodPost, `[{"method": "GET", "path": "1"}]`).Code)
+		require.Empty(t, served(t))
+	})
+
+	s.Test(`batches can't be nested`, func(t *testcase.T) {
+		rs := responses(t, serve(t, http.MethodPost, `[{"method": "POST", "path": "/:batch", "body": []}]`))
+		require.Equal(t, []int{http.StatusBadRequest}, statuses(rs))
+	})
+
+	s.When(`batch is not enabled`, func(s *testcase.Spec) {
+		s.Let(`batch`, func(t *testcase.T) interface{} { return (*gorest.BatchHandler)(nil) })
+
+		s.Then(`the path is a resource path`, func(t *testcase.T) {
+			require.Equal(t, http.StatusMethodNotAllowed, serve(t, http.MethodPost, items).Code)
+		})
+	})
+}
+
+type batchLog struct {
+	mutex sync.Mutex
+	log   []string
+}
+
+func (l *batchLog) add(entry string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.log = append(l.log, entry)
+}
+
+func (l *batchLog) entries() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.log...)
+}
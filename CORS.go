@@ -0,0 +1,163 @@
+package gorest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS represents a cross-origin resource sharing policy.
+// The allowed methods are not configured, but derived from the operations registered on the Handler,
+// so the policy can't get out of sync with the controller.
+type CORS struct {
+	// AllowedOrigins is the list of origins that is allowed to make cross-origin requests.
+	// The "*" value allows every origin.
+	AllowedOrigins []string
+	// AllowedHeaders is the list of non simple headers the requester can use during the cross-origin request.
+	// The "*" value allows every requested header.
+	AllowedHeaders []string
+	// ExposedHeaders is the list of headers that the requester is allowed to access from the response.
+	ExposedHeaders []string
+	// AllowCredentials indicates whether the request can include user credentials like cookies or authorization headers.
+	AllowCredentials bool
+	// MaxAge tells how long the result of a preflight request can be cached.
+	MaxAge time.Duration
+}
+
+const (
+	headerOrigin                        = `Origin`
+	headerVary                          = `Vary`
+	headerAccessControlRequestMethod    = `Access-Control-Request-Method`
+	headerAccessControlRequestHeaders   = `Access-Control-Request-Headers`
+	headerAccessControlAllowOrigin      = `Access-Control-Allow-Origin`
+	headerAccessControlAllowMethods     = `Access-Control-Allow-Methods`
+	headerAccessControlAllowHeaders     = `Access-Control-Allow-Headers`
+	headerAccessControlAllowCredentials = `Access-Control-Allow-Credentials`
+	headerAccessControlExposeHeaders    = `Access-Control-Expose-Headers`
+	headerAccessControlMaxAge           = `Access-Control-Max-Age`
+)
+
+type ctxKeyCORS struct{}
+
+func corsFromContext(ctx context.Context) (*CORS, bool) {
+	c, ok := ctx.Value(ctxKeyCORS{}).(*CORS)
+	return c, ok
+}
+
+func isPreflightRequest(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get(headerOrigin) != `` &&
+		r.Header.Get(headerAccessControlRequestMethod) != ``
+}
+
+// apply sets the cross-origin headers of the response and stores the policy in the request context.
+// Headers set by an outer Handler's policy are overwritten, so the most inner policy is in effect.
+func (c *CORS) apply(w http.ResponseWriter, r *http.Request) *http.Request {
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeyCORS{}, c))
+
+	header := w.Header()
+	// the response depends on the origin unless every origin gets the wildcard,
+	// so caches must not share it between origins, including the requests without one.
+	if !c.allowsAnyOrigin() || c.AllowCredentials {
+		addVary(header, headerOrigin)
+	}
+
+	origin := r.Header.Get(headerOrigin)
+	if origin == `` {
+		return r
+	}
+
+	header.Del(headerAccessControlAllowOrigin)
+	header.Del(headerAccessControlAllowCredentials)
+	header.Del(headerAccessControlExposeHeaders)
+
+	if !c.isOriginAllowed(origin) {
+		return r
+	}
+
+	if c.allowsAnyOrigin() && !c.AllowCredentials {
+		header.Set(headerAccessControlAllowOrigin, `*`)
+	} else {
+		header.Set(headerAccessControlAllowOrigin, origin)
+	}
+	if c.AllowCredentials {
+		header.Set(headerAccessControlAllowCredentials, `true`)
+	}
+	if len(c.ExposedHeaders) > 0 {
+		header.Set(headerAccessControlExposeHeaders, strings.Join(c.ExposedHeaders, `, `))
+	}
+	return r
+}
+
+// preflight sets the response headers of a preflight request.
+// When the requested method or headers are not allowed, the access control headers are left out,
+// and the user agent will reject the cross-origin request.
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, methods []string) {
+	header := w.Header()
+	addVary(header, headerAccessControlRequestMethod)
+	addVary(header, headerAccessControlRequestHeaders)
+
+	if !c.isOriginAllowed(r.Header.Get(headerOrigin)) {
+		return
+	}
+	if !containsFold(methods, r.Header.Get(headerAccessControlRequestMethod)) {
+		return
+	}
+
+	requestedHeaders := splitHeaderValues(r.Header.Get(headerAccessControlRequestHeaders))
+	for _, rh := range requestedHeaders {
+		if !c.isHeaderAllowed(rh) {
+			return
+		}
+	}
+
+	header.Set(headerAccessControlAllowMethods, strings.Join(methods, `, `))
+	if len(requestedHeaders) > 0 {
+		header.Set(headerAccessControlAllowHeaders, strings.Join(requestedHeaders, `, `))
+	}
+	if 0 < c.MaxAge {
+		header.Set(headerAccessControlMaxAge, strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+}
+
+func (c *CORS) allowsAnyOrigin() bool {
+	return containsFold(c.AllowedOrigins, `*`)
+}
+
+func (c *CORS) isOriginAllowed(origin string) bool {
+	return origin != `` && (c.allowsAnyOrigin() || containsFold(c.AllowedOrigins, origin))
+}
+
+func (c *CORS) isHeaderAllowed(header string) bool {
+	return containsFold(c.AllowedHeaders, `*`) || containsFold(c.AllowedHeaders, header)
+}
+
+func addVary(header http.Header, value string) {
+	for _, v := range header[headerVary] {
+		if containsFold(splitHeaderValues(v), value) {
+			return
+		}
+	}
+	header.Add(headerVary, value)
+}
+
+func splitHeaderValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, `,`) {
+		if v = strings.TrimSpace(v); v != `` {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package gorest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestCORS(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`policy`, func(t *testcase.T) interface{} {
+		return &gorest.CORS{
+			AllowedOrigins: []string{`https://example.com`},
+			AllowedHeaders: []string{`Content-Type`, `Authorization`},
+			ExposedHeaders: []string{`X-Total-Count`},
+			MaxAge:         10 * time.Minute,
+		}
+	})
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		h := gorest.NewHandler(StubController{})
+		h.CORS = t.I(`policy`).(*gorest.CORS)
+		return h
+	})
+	s.Let(`origin`, func(t *testcase.T) interface{} { return `https://example.com` })
+
+	var serve = func(t *testcase.T, r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		t.I(`handler`).(http.Handler).ServeHTTP(w, r)
+		return w
+	}
+
+	var preflight = func(t *testcase.T, path, method, headers string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodOptions, path, nil)
+		r.Header.Set(`Origin`, t.I(`origin`).(string))
+		r.Header.Set(`Access-Control-Request-Method`, method)
+		if headers != `` {
+			r.Header.Set(`Access-Control-Request-Headers`, headers)
+		}
+		return serve(t, r)
+	}
+
+	s.Describe(`preflight request`, func(s *testcase.Spec) {
+		s.Then(`the allowed methods are derived from the collection operations`, func(t *testcase.T) {
+			resp := preflight(t, `/`, http.MethodPost, `Content-Type`)
+			require.Equal(t, http.StatusNoContent, resp.Code)
+			require.Equal(t, `GET, HEAD, OPTIONS, POST`, resp.Header().Get(`Access-Control-Allow-Methods`))
+			require.Equal(t, `https://example.com`, resp.Header().Get(`Access-Control-Allow-Origin`))
+			require.Equal(t, `Content-Type`, resp.Header().Get(`Access-Control-Allow-Headers`))
+			require.Equal(t, `600`, resp.Header().Get(`Access-Control-Max-Age`))
+		})
+
+		s.Then(`the allowed methods are derived from the resource operations`, func(t *testcase.T) {
+			resp := preflight(t, `/42`, http.MethodDelete, ``)
+			require.Equal(t, http.StatusNoContent, resp.Code)
+			require.Equal(t, `DELETE, GET, HEAD, OPTIONS, PATCH, PUT`, resp.Header().Get(`Access-Control-Allow-Methods`))
+		})
+
+		s.Then(`a method that is not registered is not allowed`, func(t *testcase.T) {
+			resp := preflight(t, `/`, http.MethodDelete, ``)
+			require.Empty(t, resp.Header().Get(`Access-Control-Allow-Methods`))
+		})
+
+		s.Then(`a header that is not whitelisted is not allowed`, func(t *testcase.T) {
+			resp := preflight(t, `/`, http.MethodPost, `X-Custom`)
+			require.Empty(t, resp.Header().Get(`Access-Control-Allow-Methods`))
+		})
+
+		s.When(`the resource is only found with credentials`, func(s *testcase.Spec) {
+			s.Let(`handler`, func(t *testcase.T) interface{} {
+				h := gorest.NewHandler(StubController{
+					ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+						return ctx, false, nil
+					},
+				})
+				h.CORS = t.I(`policy`).(*gorest.CORS)
+				return h
+			})
+
+			s.Then(`the preflight is answered without the resource lookup`, func(t *testcase.T) {
+				resp := preflight(t, `/42`, http.MethodDelete, ``)
+				require.Equal(t, http.StatusNoContent, resp.Code)
+				require.Equal(t, `DELETE, GET, HEAD, OPTIONS, PATCH, PUT`, resp.Header().Get(`Access-Control-Allow-Methods`))
+			})
+
+			s.Then(`the preflight of a mounted sub collection is passed on without the resource lookup`, func(t *testcase.T) {
+				gorest.Mount(t.I(`handler`).(*gorest.Handler), `/orgs`, gorest.NewHandler(StubController{}))
+
+				resp := preflight(t, `/42/orgs`, http.MethodPost, ``)
+				require.Equal(t, http.StatusNoContent, resp.Code)
+				require.Equal(t, `GET, HEAD, OPTIONS, POST`, resp.Header().Get(`Access-Control-Allow-Methods`))
+
+				resp = preflight(t, `/42/orgs/1`, http.MethodDelete, ``)
+				require.Equal(t, http.StatusNoContent, resp.Code)
+				require.Equal(t, `DELETE, GET, HEAD, OPTIONS, PATCH, PUT`, resp.Header().Get(`Access-Control-Allow-Methods`))
+			})
+
+			s.Then(`other requests still look up the resource`, func(t *testcase.T) {
+				r := httptest.NewRequest(http.MethodOptions, `/42`, nil)
+				require.Equal(t, http.StatusNotFound, serve(t, r).Code)
+			})
+		})
+
+		s.When(`origin is not allowed`, func(s *testcase.Spec) {
+			s.Let(`origin`, func(t *testcase.T) interface{} { return `https://evil.com` })
+
+			s.Then(`no access control headers are set`, func(t *testcase.T) {
+				resp := preflight(t, `/`, http.MethodPost, ``)
+				require.Empty(t, resp.Header().Get(`Access-Control-Allow-Origin`))
+				require.Empty(t, resp.Header().Get(`Access-Control-Allow-Methods`))
+			})
+		})
+	})
+
+	s.Describe(`cross-origin request`, func(s *testcase.Spec) {
+		s.Then(`it will set the origin related headers`, func(t *testcase.T) {
+			r := httptest.NewRequest(http.MethodGet, `/`, nil)
+			r.Header.Set(`Origin`, t.I(`origin`).(string))
+			resp := serve(t, r)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Equal(t, `https://example.com`, resp.Header().Get(`Access-Control-Allow-Origin`))
+			require.Equal(t, `X-Total-Count`, resp.Header().Get(`Access-Control-Expose-Headers`))
+			require.Contains(t, resp.Header().Get(`Vary`), `Origin`)
+		})
+
+		s.Then(`the response varies by origin even when the origin is missing or not allowed`, func(t *testcase.T) {
+			r := httptest.NewRequest(http.MethodGet, `/`, nil)
+			require.Contains(t, serve(t, r).Header().Get(`Vary`), `Origin`)
+
+			r = httptest.NewRequest(http.MethodGet, `/`, nil)
+			r.Header.Set(`Origin`, `https://evil.com`)
+			resp := serve(t, r)
+			require.Empty(t, resp.Header().Get(`Access-Control-Allow-Origin`))
+			require.Contains(t, resp.Header().Get(`Vary`), `Origin`)
+		})
+
+		s.When(`any origin is allowed`, func(s *testcase.Spec) {
+			s.Let(`policy`, func(t *testcase.T) interface{} {
+				return &gorest.CORS{AllowedOrigins: []string{`*`}}
+			})
+
+			s.Then(`wildcard is used`, func(t *testcase.T) {
+				r := httptest.NewRequest(http.MethodGet, `/`, nil)
+				r.Header.Set(`Origin`, `https://whatever.com`)
+				resp := serve(t, r)
+				require.Equal(t, `*`, resp.Header().Get(`Access-Control-Allow-Origin`))
+				require.NotContains(t, resp.Header().Get(`Vary`), `Origin`)
+			})
+		})
+	})
+
+	s.Describe(`mounted sub handler`, func(s *testcase.Spec) {
+		s.Let(`sub-policy`, func(t *testcase.T) interface{} {
+			return &gorest.CORS{AllowedOrigins: []string{`https://sub.example.com`}}
+		})
+		s.Before(func(t *testcase.T) {
+			sub := gorest.NewHandler(StubController{})
+			sub.CORS = t.I(`sub-policy`).(*gorest.CORS)
+			gorest.Mount(t.I(`handler`).(*gorest.Handler), `/subs`, sub)
+		})
+
+		s.Then(`the sub handler policy is enforced`, func(t *testcase.T) {
+			r := httptest.NewRequest(http.MethodGet, `/42/subs/24`, nil)
+			r.Header.Set(`Origin`, `https://example.com`)
+			require.Empty(t, serve(t, r).Header().Get(`Access-Control-Allow-Origin`))
+
+			r = httptest.NewRequest(http.MethodGet, `/42/subs/24`, nil)
+			r.Header.Set(`Origin`, `https://sub.example.com`)
+			require.Equal(t, `https://sub.example.com`, serve(t, r).Header().Get(`Access-Control-Allow-Origin`))
+		})
+
+		s.When(`sub handler has no policy`, func(s *testcase.Spec) {
+			s.Let(`sub-policy`, func(t *testcase.T) interface{} { return (*gorest.CORS)(nil) })
+
+			s.Then(`the parent policy is used`, func(t *testcase.T) {
+				resp := preflight(t, `/42/subs`, http.MethodPost, ``)
+				require.Equal(t, http.StatusNoContent, resp.Code)
+				require.Equal(t, `GET, HEAD, OPTIONS, POST`, resp.Header().Get(`Access-Control-Allow-Methods`))
+				require.Equal(t, `https://example.com`, resp.Header().Get(`Access-Control-Allow-Origin`))
+			})
+		})
+	})
+}
@@ -0,0 +1,278 @@
+package gorest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedMediaType is returned by Decode when no codec is registered for the request Content-Type.
+	ErrUnsupportedMediaType = errors.New(`gorest: unsupported media type`)
+	// ErrNotAcceptable is returned by Encode when no codec satisfies the request Accept header.
+	ErrNotAcceptable = errors.New(`gorest: not acceptable`)
+)
+
+// Codec encodes and decodes values in a given media type.
+type Codec interface {
+	// MediaType returns the media type the codec handles, like "application/json".
+	MediaType() string
+	// Encode writes v in the media type of the codec.
+	// When the media type can't represent v, the returned error should match ErrNotAcceptable,
+	// so the next acceptable codec can be tried.
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// Codecs is a registry of codecs in the order of preference.
+// The first registered codec is used when the requester has no preference.
+type Codecs struct {
+	codecs []Codec
+}
+
+// NewCodecs creates a codec registry with the given codecs in the order of preference.
+func NewCodecs(codecs ...Codec) *Codecs {
+	c := &Codecs{}
+	for _, codec := range codecs {
+		c.Register(codec)
+	}
+	return c
+}
+
+// DefaultCodecs creates a codec registry with JSON, XML, form-urlencoded and plain text codecs.
+func DefaultCodecs() *Codecs {
+	return NewCodecs(JSONCodec{}, XMLCodec{}, FormCodec{}, TextCodec{})
+}
+
+var defaultCodecs = DefaultCodecs()
+
+// Register adds a codec to the registry.
+// If a codec is already registered for the media type, it is replaced.
+func (c *Codecs) Register(codec Codec) {
+	for i, registered := range c.codecs {
+		if strings.EqualFold(registered.MediaType(), codec.MediaType()) {
+			c.codecs[i] = codec
+			return
+		}
+	}
+	c.codecs = append(c.codecs, codec)
+}
+
+// Lookup finds the codec for a media type.
+// Media type parameters like charset are ignored.
+func (c *Codecs) Lookup(mediaType string) (Codec, bool) {
+	if mt, _, err := mime.ParseMediaType(mediaType); err == nil {
+		mediaType = mt
+	}
+	for _, codec := range c.codecs {
+		if strings.EqualFold(codec.MediaType(), mediaType) {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+// Negotiate selects the most preferred codec for the value of an Accept header.
+// An empty Accept header means the requester accepts anything.
+func (c *Codecs) Negotiate(accept string) (Codec, bool) {
+	codecs := c.acceptable(accept)
+	if len(codecs) == 0 {
+		return nil, false
+	}
+	return codecs[0], true
+}
+
+// acceptable returns the codecs that satisfy the value of an Accept header, in the order of preference.
+func (c *Codecs) acceptable(accept string) []Codec {
+	if strings.TrimSpace(accept) == `` {
+		return c.codecs
+	}
+
+	var codecs []Codec
+	ranges := parseAccept(accept)
+	for _, mr := range ranges {
+		if mr.q == 0 {
+			continue
+		}
+		for _, codec := range c.codecs {
+			if mr.match(codec.MediaType()) && !isExcluded(ranges, codec.MediaType()) && !containsCodec(codecs, codec) {
+				codecs = append(codecs, codec)
+			}
+		}
+	}
+	return codecs
+}
+
+func containsCodec(codecs []Codec, codec Codec) bool {
+	for _, c := range codecs {
+		if strings.EqualFold(c.MediaType(), codec.MediaType()) {
+			return true
+		}
+	}
+	return false
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+func (mr mediaRange) match(mediaType string) bool {
+	if mr.mediaType == `*/*` {
+		return true
+	}
+	if strings.HasSuffix(mr.mediaType, `/*`) {
+		return strings.HasPrefix(strings.ToLower(mediaType), strings.TrimSuffix(mr.mediaType, `*`))
+	}
+	return strings.EqualFold(mr.mediaType, mediaType)
+}
+
+// parseAccept parses the media ranges of an Accept header, ordered by quality and specificity.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, `,`) {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		mr := mediaRange{mediaType: strings.ToLower(mt), q: 1}
+		if q, ok := params[`q`]; ok {
+			if f, err := strconv.ParseFloat(q, 64); err == nil {
+				mr.q = f
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return strings.Count(ranges[i].mediaType, `*`) < strings.Count(ranges[j].mediaType, `*`)
+	})
+	return ranges
+}
+
+// isExcluded reports whether the media type is explicitly refused with a zero quality.
+func isExcluded(ranges []mediaRange, mediaType string) bool {
+	for _, mr := range ranges {
+		if mr.q == 0 && strings.EqualFold(mr.mediaType, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+type ctxKeyCodecs struct{}
+
+func codecsFromContext(ctx context.Context) *Codecs {
+	if c, ok := ctx.Value(ctxKeyCodecs{}).(*Codecs); ok {
+		return c
+	}
+	return defaultCodecs
+}
+
+// Decode decodes the request body into v with the codec selected by the Content-Type header.
+// The codecs of the Handler serving the request are used, or DefaultCodecs when the Handler has none.
+// A request without Content-Type is decoded with the most preferred codec.
+//
+// ErrUnsupportedMediaType is returned when no codec is registered for the Content-Type,
+// and an error that matches ErrBadRequest is returned when the body can't be decoded.
+func Decode(r *http.Request, v interface{}) error {
+	codecs := codecsFromContext(r.Context())
+
+	var (
+		codec Codec
+		ok    bool
+	)
+	if ct := r.Header.Get(`Content-Type`); ct != `` {
+		codec, ok = codecs.Lookup(ct)
+	} else {
+		codec, ok = codecs.Negotiate(``)
+	}
+	if !ok {
+		return ErrUnsupportedMediaType
+	}
+
+	if err := codec.Decode(r.Body, v); err != nil {
+		return &decodeError{mediaType: codec.MediaType(), err: err}
+	}
+	return nil
+}
+
+// decodeError is a request body that couldn't be decoded.
+// It is reported as ErrBadRequest, while it unwraps to the error of the codec.
+type decodeError struct {
+	mediaType string
+	err       error
+}
+
+func (err *decodeError) Error() string {
+	return fmt.Sprintf(`gorest: decoding request body as %s: %s`, err.mediaType, err.err)
+}
+
+func (err *decodeError) Unwrap() error { return err.err }
+
+func (err *decodeError) Is(target error) bool { return target == ErrBadRequest }
+
+// Encode writes v as the response body with the status code,
+// using the codec that is the most preferred by the request Accept header.
+// The codecs of the Handler serving the request are used, or DefaultCodecs when the Handler has none.
+// The body is encoded before the status is written, so a failed encoding doesn't send a broken response.
+//
+// When no codec is acceptable for the requester, or none of the acceptable codecs can represent v,
+// a 406 Not Acceptable response is written, and an error that matches ErrNotAcceptable is returned.
+// Other encoding errors are replied with WriteError, and returned.
+//
+// When the request has a field mask for the response, a JSON response is shaped by the mask.
+func Encode(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	addVary(w.Header(), `Accept`)
+
+	var (
+		buf bytes.Buffer
+		err error = ErrNotAcceptable
+	)
+	for _, codec := range codecsFromContext(r.Context()).acceptable(r.Header.Get(`Accept`)) {
+		value := v
+		state, ok := fieldMaskStateFromContext(r.Context())
+		shape := ok && state.shape && isJSONMediaType(codec.MediaType())
+		if shape {
+			value, err = state.mask.Apply(v)
+			if err != nil {
+				WriteError(w, r, err)
+				return err
+			}
+		}
+
+		buf.Reset()
+		err = codec.Encode(&buf, value)
+		if errors.Is(err, ErrNotAcceptable) {
+			continue
+		}
+		if err != nil {
+			WriteError(w, r, err)
+			return err
+		}
+
+		if shape {
+			state.shaped = true
+		}
+		contentType := codec.MediaType()
+		if strings.HasPrefix(contentType, `text/`) {
+			contentType += `; charset=utf-8`
+		}
+		w.Header().Set(`Content-Type`, contentType)
+		w.WriteHeader(status)
+		_, err = w.Write(buf.Bytes())
+		return err
+	}
+
+	httpError(w, r, http.StatusNotAcceptable, ``)
+	return err
+}
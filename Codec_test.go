@@ -0,0 +1,259 @@
+package gorest_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+type CodecTestEntity struct {
+	Name string   `json:"name" xml:"name" form:"name"`
+	Age  int      `json:"age" xml:"age" form:"age"`
+	Tags []string `json:"tags" xml:"tags" form:"tag"`
+}
+
+func TestCodecs_Negotiate(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	var subject = func(t *testcase.T) (gorest.Codec, bool) {
+		return gorest.DefaultCodecs().Negotiate(t.I(`accept`).(string))
+	}
+
+	var thenMediaType = func(s *testcase.Spec, accept, expected string) {
+		s.When(`accept is `+accept, func(s *testcase.Spec) {
+			s.Let(`accept`, func(t *testcase.T) interface{} { return accept })
+
+			s.Then(`it will select `+expected, func(t *testcase.T) {
+				codec, ok := subject(t)
+				require.True(t, ok)
+				require.Equal(t, expected, codec.MediaType())
+			})
+		})
+	}
+
+	thenMediaType(s, ``, `application/json`)
+	thenMediaType(s, `*/*`, `application/json`)
+	thenMediaType(s, `application/xml`, `application/xml`)
+	thenMediaType(s, `text/*`, `text/plain`)
+	thenMediaType(s, `application/json;q=0.5, application/xml`, `application/xml`)
+	thenMediaType(s, `application/json;q=0, */*`, `application/xml`)
+	thenMediaType(s, `text/html, application/x-www-form-urlencoded;q=0.1`, `application/x-www-form-urlencoded`)
+
+	s.When(`nothing acceptable`, func(s *testcase.Spec) {
+		s.Let(`accept`, func(t *testcase.T) interface{} { return `image/png` })
+
+		s.Then(`it will report it`, func(t *testcase.T) {
+			_, ok := subject(t)
+			require.False(t, ok)
+		})
+	})
+}
+
+func TestEncodeDecode(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`codecs`, func(t *testcase.T) interface{} { return (*gorest.Codecs)(nil) })
+	s.Let(`content-type`, func(t *testcase.T) interface{} { return `` })
+	s.Let(`accept`, func(t *testcase.T) interface{} { return `` })
+	s.Let(`body`, func(t *testcase.T) interface{} { return `` })
+
+	var serve = func(t *testcase.T) *httptest.ResponseRecorder {
+		h := gorest.NewHandler(gorest.AsCreateController(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var e CodecTestEntity
+			err := gorest.Decode(r, &e)
+			t.Let(`decode error`, err)
+			if err != nil {
+				return
+			}
+			t.Let(`encode error`, gorest.Encode(w, r, http.StatusCreated, e))
+		})))
+		h.Codecs = t.I(`codecs`).(*gorest.Codecs)
+
+		r := httptest.NewRequest(http.MethodPost, `/`, strings.NewReader(t.I(`body`).(string)))
+		if ct := t.I(`content-type`).(string); ct != `` {
+			r.Header.Set(`Content-Type`, ct)
+		}
+		if accept := t.I(`accept`).(string); accept != `` {
+			r.Header.Set(`Accept`, accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	s.When(`JSON is sent and accepted`, func(s *testcase.Spec) {
+		s.Let(`content-type`, func(t *testcase.T) interface{} { return `application/json; charset=utf-8` })
+		s.Let(`accept`, func(t *testcase.T) interface{} { return `application/json` })
+		s.Let(`body`, func(t *testcase.T) interface{} { return `{"name":"Jane","age":42,"tags":["a"]}` })
+
+		s.Then(`it will round trip the entity`, func(t *testcase.T) {
+			resp := serve(t)
+			require.Equal(t, http.StatusCreated, resp.Code)
+			require.Equal(t, `application/json`, resp.Header().Get(`Content-Type`))
+			require.JSONEq(t, `{"name":"Jane","age":42,"tags":["a"]}`, resp.Body.String())
+			require.Contains(t, resp.Header().Get(`Vary`), `Accept`)
+		})
+	})
+
+	s.When(`form is sent and XML is accepted`, func(s *testcase.Spec) {
+		s.Let(`content-type`, func(t *testcase.T) interface{} { return `application/x-www-form-urlencoded` })
+		s.Let(`accept`, func(t *testcase.T) interface{} { return `application/xml` })
+		s.Let(`body`, func(t *testcase.T) interface{} { return `name=Jane&age=42&tag=a&tag=b` })
+
+		s.Then(`it will decode the form and encode XML`, func(t *testcase.T) {
+			resp := serve(t)
+			require.Equal(t, http.StatusCreated, resp.Code)
+			require.Equal(t, `application/xml`, resp.Header().Get(`Content-Type`))
+			require.Equal(t, `<CodecTestEntity><name>Jane</name><age>42</age><tags>a</tags><tags>b</tags></CodecTestEntity>`, resp.Body.String())
+		})
+	})
+
+	s.When(`the content type is not supported`, func(s *testcase.Spec) {
+		s.Let(`content-type`, func(t *testcase.T) interface{} { return `application/msgpack` })
+
+		s.Then(`decode reports unsupported media type`, func(t *testcase.T) {
+			serve(t)
+			require.True(t, errors.Is(t.I(`decode error`).(error), gorest.ErrUnsupportedMediaType))
+		})
+	})
+
+	s.When(`nothing in the accept header can be produced`, func(s *testcase.Spec) {
+		s.Let(`body`, func(t *testcase.T) interface{} { return `{}` })
+		s.Let(`accept`, func(t *testcase.T) interface{} { return `image/png` })
+
+		s.Then(`encode replies with not acceptable`, func(t *testcase.T) {
+			resp := serve(t)
+			require.Equal(t, http.StatusNotAcceptable, resp.Code)
+			require.True(t, errors.Is(t.I(`encode error`).(error), gorest.ErrNotAcceptable))
+		})
+	})
+
+	s.When(`the handler has its own codecs`, func(s *testcase.Spec) {
+		s.Let(`codecs`, func(t *testcase.T) interface{} { return gorest.NewCodecs(gorest.XMLCodec{}) })
+		s.Let(`body`, func(t *testcase.T) interface{} { return `<CodecTestEntity><name>Jane</name></CodecTestEntity>` })
+
+		s.Then(`the handler codecs are used`, func(t *testcase.T) {
+			resp := serve(t)
+			require.Equal(t, http.StatusCreated, resp.Code)
+			require.Equal(t, `application/xml`, resp.Header().Get(`Content-Type`))
+		})
+
+		s.And(`json is sent`, func(s *testcase.Spec) {
+			s.Let(`content-type`, func(t *testcase.T) interface{} { return `application/json` })
+
+			s.Then(`it is not supported`, func(t *testcase.T) {
+				serve(t)
+				require.True(t, errors.Is(t.I(`decode error`).(error), gorest.ErrUnsupportedMediaType))
+			})
+		})
+	})
+}
+
+func TestFormCodec(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`struct round trip`, func(t *testcase.T) {
+		var buf bytes.Buffer
+		require.Nil(t, gorest.FormCodec{}.Encode(&buf, CodecTestEntity{Name: `Jane`, Age: 42, Tags: []string{`a`, `b`}}))
+		require.Equal(t, `age=42&name=Jane&tag=a&tag=b`, buf.String())
+
+		var e CodecTestEntity
+		require.Nil(t, gorest.FormCodec{}.Decode(&buf, &e))
+		require.Equal(t, CodecTestEntity{Name: `Jane`, Age: 42, Tags: []string{`a`, `b`}}, e)
+	})
+
+	s.Test(`maps`, func(t *testcase.T) {
+		var m map[string]string
+		require.Nil(t, gorest.FormCodec{}.Decode(strings.NewReader(`a=1&b=2`), &m))
+		require.Equal(t, map[string]string{`a`: `1`, `b`: `2`}, m)
+
+		var vs url.Values
+		require.Nil(t, gorest.FormCodec{}.Decode(strings.NewReader(`a=1&a=2`), &vs))
+		require.Equal(t, []string{`1`, `2`}, vs[`a`])
+	})
+
+	s.Test(`invalid value`, func(t *testcase.T) {
+		var e CodecTestEntity
+		require.Error(t, gorest.FormCodec{}.Decode(strings.NewReader(`age=abc`), &e))
+	})
+}
+
+func TestTextCodec(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`round trip`, func(t *testcase.T) {
+		var buf bytes.Buffer
+		require.Nil(t, gorest.TextCodec{}.Encode(&buf, 42))
+		var str string
+		require.Nil(t, gorest.TextCodec{}.Decode(&buf, &str))
+		require.Equal(t, `42`, str)
+	})
+
+	s.Test(`encode with Encode sets charset`, func(t *testcase.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, `/`, nil)
+		r.Header.Set(`Accept`, `text/plain`)
+		require.Nil(t, gorest.Encode(w, r, http.StatusOK, `hello`))
+		require.Equal(t, `text/plain; charset=utf-8`, w.Header().Get(`Content-Type`))
+		require.Equal(t, `hello`, w.Body.String())
+	})
+}
+
+func TestEncode(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`accept`, func(t *testcase.T) interface{} { return `` })
+	var encode = func(t *testcase.T, v interface{}) (*httptest.ResponseRecorder, error) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, `/`, nil)
+		r.Header.Set(`Accept`, t.I(`accept`).(string))
+		err := gorest.Encode(w, r, http.StatusOK, v)
+		return w, err
+	}
+
+	var thenNotAcceptable = func(s *testcase.Spec, accept string, v interface{}) {
+		s.When(`accept is `+accept, func(s *testcase.Spec) {
+			s.Let(`accept`, func(t *testcase.T) interface{} { return accept })
+
+			s.Then(`a value that the codec can't represent is not acceptable`, func(t *testcase.T) {
+				resp, err := encode(t, v)
+				require.True(t, errors.Is(err, gorest.ErrNotAcceptable))
+				require.Equal(t, http.StatusNotAcceptable, resp.Code)
+			})
+		})
+	}
+
+	thenNotAcceptable(s, `application/x-www-form-urlencoded`, []CodecTestEntity{{Name: `Jane`}})
+	thenNotAcceptable(s, `application/xml`, map[string]int{`a`: 1})
+	thenNotAcceptable(s, `application/xml`, []CodecTestEntity{{Name: `Jane`}, {Name: `John`}})
+	thenNotAcceptable(s, `text/plain`, []CodecTestEntity{{Name: `Jane`}})
+
+	s.When(`the most preferred codec can't represent the value`, func(s *testcase.Spec) {
+		s.Let(`accept`, func(t *testcase.T) interface{} { return `text/plain, application/json;q=0.5` })
+
+		s.Then(`the next acceptable codec is used`, func(t *testcase.T) {
+			resp, err := encode(t, []CodecTestEntity{{Name: `Jane`}})
+			require.Nil(t, err)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Equal(t, `application/json`, resp.Header().Get(`Content-Type`))
+		})
+	})
+
+	s.When(`the encoding fails`, func(s *testcase.Spec) {
+		s.Then(`no partial response is written, and the error is replied`, func(t *testcase.T) {
+			resp, err := encode(t, map[string]interface{}{`ch`: make(chan int)})
+			require.Error(t, err)
+			require.Equal(t, http.StatusInternalServerError, resp.Code)
+			require.NotContains(t, resp.Header().Get(`Content-Type`), `application/json`)
+		})
+	})
+}
@@ -0,0 +1,45 @@
+package gorest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrRouteConflict is returned when a route can't be registered, because it would shadow another route.
+var ErrRouteConflict = errors.New(`gorest: route conflict`)
+
+// HandleCollection registers a static collection level endpoint like /search or /export,
+// which is matched before the resource id is taken from the path,
+// so the requests of the endpoint never reach ContextWithResource.
+// The handler is served as an OpCustom operation, so the Authorizer and the UseFor middlewares apply to it.
+//
+// example:
+//
+//	err := h.HandleCollection(`/search`, searchHandler) // GET /users/search
+//
+// The first path segment of the pattern is reserved for the endpoint.
+// When the ContextHandler implements ResourceIDValidator, and the segment is a valid resource id,
+// the registration fails with ErrRouteConflict, as the route would make a resource unreachable.
+// It also fails when the pattern is already registered.
+func (h *Handler) HandleCollection(pattern string, handler http.Handler) error {
+	if !strings.HasPrefix(pattern, `/`) {
+		pattern = `/` + pattern
+	}
+	name := h.collectionHandlers.prefix(pattern)
+	if name == `` {
+		return fmt.Errorf(`gorest: invalid collection route pattern: %q`, pattern)
+	}
+	for _, rh := range h.collectionHandlers.registered {
+		if rh.pattern == pattern {
+			return fmt.Errorf(`%w: collection route %q is already registered`, ErrRouteConflict, pattern)
+		}
+	}
+	if v, ok := h.ContextHandler.(ResourceIDValidator); ok && v.ValidResourceID(name) {
+		return fmt.Errorf(`%w: collection route %q would shadow the resource with the id %q`, ErrRouteConflict, pattern, name)
+	}
+
+	h.collectionHandlers.Handle(pattern, customOperation{handler: handler, owner: h})
+	return nil
+}
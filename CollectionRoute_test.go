@@ -0,0 +1,99 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestHandler_HandleCollection(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`resource ids`, func(t *testcase.T) interface{} { return &[]string{} })
+	var resourceIDs = func(t *testcase.T) []string { return *t.I(`resource ids`).(*[]string) }
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		return gorest.NewHandler(StubController{
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				ptr := t.I(`resource ids`).(*[]string)
+				*ptr = append(*ptr, id)
+				return ctx, true, nil
+			},
+			ShowFunc: func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, `show`) },
+		})
+	})
+	var handler = func(t *testcase.T) *gorest.Handler { return t.I(`handler`).(*gorest.Handler) }
+	var search = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, `search`) })
+	var serve = func(t *testcase.T, h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	s.Test(`collection route is matched before the resource id`, func(t *testcase.T) {
+		require.Nil(t, handler(t).HandleCollection(`/search`, search))
+		require.Equal(t, `search`, serve(t, handler(t), `/search`).Body.String())
+		require.Empty(t, resourceIDs(t))
+		require.Equal(t, `show`, serve(t, handler(t), `/42`).Body.String())
+		require.Equal(t, []string{`42`}, resourceIDs(t))
+	})
+
+	s.Test(`collection route is an operation`, func(t *testcase.T) {
+		require.Nil(t, handler(t).HandleCollection(`/search`, search))
+		handler(t).Authorizer = gorest.AuthorizerFunc(func(r *http.Request, op gorest.Operation) (gorest.Authorization, error) {
+			if op == gorest.OpCustom {
+				return gorest.Deny, nil
+			}
+			return gorest.Allow, nil
+		})
+		require.Equal(t, http.StatusForbidden, serve(t, handler(t), `/search`).Code)
+	})
+
+	s.Test(`mounted collection route`, func(t *testcase.T) {
+		require.Nil(t, handler(t).HandleCollection(`/search`, search))
+		parent := gorest.NewHandler(StubController{})
+		gorest.Mount(parent, `/users`, handler(t))
+		require.Equal(t, `search`, serve(t, parent, `/1/users/search`).Body.String())
+		require.Empty(t, resourceIDs(t))
+	})
+
+	s.Test(`routes`, func(t *testcase.T) {
+		require.Nil(t, handler(t).HandleCollection(`/search`, search))
+		var paths []string
+		for _, route := range handler(t).Routes() {
+			if route.Operation == gorest.OpCustom {
+				paths = append(paths, route.Path)
+			}
+		}
+		require.Equal(t, []string{`/search`}, paths)
+	})
+
+	s.Test(`registering a pattern twice is a conflict`, func(t *testcase.T) {
+		require.Nil(t, handler(t).HandleCollection(`/search`, search))
+		require.True(t, errors.Is(handler(t).HandleCollection(`search`, search), gorest.ErrRouteConflict))
+	})
+
+	s.When(`the ContextHandler validates the resource ids`, func(s *testcase.Spec) {
+		s.Let(`handler`, func(t *testcase.T) interface{} {
+			return gorest.NewHandler(gorest.ParsingContextHandler[int]{Parse: strconv.Atoi})
+		})
+
+		s.Then(`a route that can't be a resource id is registered`, func(t *testcase.T) {
+			require.Nil(t, handler(t).HandleCollection(`/count`, search))
+		})
+
+		s.Then(`a route that could be a resource id is a conflict`, func(t *testcase.T) {
+			err := handler(t).HandleCollection(`/2024/report`, search)
+			require.True(t, errors.Is(err, gorest.ErrRouteConflict))
+			require.Contains(t, err.Error(), `would shadow the resource with the id "2024"`)
+		})
+	})
+}
@@ -18,3 +18,10 @@ type ContextHandlerFunc func(context.Context, string) (context.Context, bool, er
 func (fn ContextHandlerFunc) ContextWithResource(ctx context.Context, resourceID string) (context.Context, bool, error) {
 	return fn(ctx, resourceID)
 }
+
+// ResourceIDValidator is an optional interface of a ContextHandler,
+// which tells whether a path segment can be a resource id, without looking up the resource.
+// Handler.HandleCollection uses it to reject the collection routes that could shadow a resource.
+type ResourceIDValidator interface {
+	ValidResourceID(resourceID string) bool
+}
@@ -0,0 +1,38 @@
+package gorest
+
+import (
+	"context"
+	"fmt"
+)
+
+// ContextKey is a type-safe context key, so values can be stored and retrieved without type assertions.
+// Keys created with NewContextKey are unique.
+// The zero value is also a valid key, which is shared between the zero value keys of the same type.
+type ContextKey[T any] struct{ id *contextKeyID }
+
+type contextKeyID struct{ name string }
+
+// NewContextKey creates a unique context key.
+// The name is only used for debugging purposes.
+func NewContextKey[T any](name string) ContextKey[T] {
+	return ContextKey[T]{id: &contextKeyID{name: name}}
+}
+
+// With returns a copy of the context that holds the value under the key.
+func (k ContextKey[T]) With(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Get returns the value stored under the key, and reports whether it was present.
+func (k ContextKey[T]) Get(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+func (k ContextKey[T]) String() string {
+	var zero T
+	if k.id == nil {
+		return fmt.Sprintf(`gorest.ContextKey[%T]`, zero)
+	}
+	return fmt.Sprintf(`gorest.ContextKey[%T](%s)`, zero, k.id.name)
+}
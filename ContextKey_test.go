@@ -0,0 +1,51 @@
+package gorest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestContextKey(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`value stored with the key can be retrieved`, func(t *testcase.T) {
+		key := gorest.NewContextKey[Teapot](`teapot`)
+		ctx := key.With(context.Background(), Teapot{})
+		v, ok := key.Get(ctx)
+		require.True(t, ok)
+		require.Equal(t, Teapot{}, v)
+	})
+
+	s.Test(`missing value is reported`, func(t *testcase.T) {
+		key := gorest.NewContextKey[int](`id`)
+		v, ok := key.Get(context.Background())
+		require.False(t, ok)
+		require.Equal(t, 0, v)
+	})
+
+	s.Test(`keys are unique even with the same name`, func(t *testcase.T) {
+		key1 := gorest.NewContextKey[int](`id`)
+		key2 := gorest.NewContextKey[int](`id`)
+		ctx := key1.With(context.Background(), 42)
+		_, ok := key2.Get(ctx)
+		require.False(t, ok)
+	})
+
+	s.Test(`zero value keys are distinct by type`, func(t *testcase.T) {
+		ctx := gorest.ContextKey[int]{}.With(context.Background(), 42)
+		v, ok := gorest.ContextKey[int]{}.Get(ctx)
+		require.True(t, ok)
+		require.Equal(t, 42, v)
+		_, ok = gorest.ContextKey[int64]{}.Get(ctx)
+		require.False(t, ok)
+	})
+
+	s.Test(`String`, func(t *testcase.T) {
+		require.Equal(t, `gorest.ContextKey[int](id)`, gorest.NewContextKey[int](`id`).String())
+	})
+}
@@ -29,12 +29,39 @@ type ShowController interface {
 	Show(w http.ResponseWriter, r *http.Request)
 }
 
+type HeadController interface {
+	// Head -- HEAD /{resourceID}
+	// Head is an optional cheaper alternative to answer HEAD requests for a resource.
+	// Without it, HEAD requests are served with Show, and the response body is discarded.
+	Head(w http.ResponseWriter, r *http.Request)
+}
+
+type ListHeadController interface {
+	// ListHead -- HEAD /
+	// ListHead is an optional cheaper alternative to answer HEAD requests for the collection.
+	// Without it, HEAD requests are served with List, and the response body is discarded.
+	ListHead(w http.ResponseWriter, r *http.Request)
+}
+
 type UpdateController interface {
-	// Update -- PUT /{resourceID}
+	// Update -- PUT|PATCH /{resourceID}
 	// Update expected to update the properties of a received resource that is identified by id.
+	// When the controller implements ReplaceController or PatchController, those take precedence over Update.
 	Update(w http.ResponseWriter, r *http.Request)
 }
 
+type ReplaceController interface {
+	// Replace -- PUT /{resourceID}
+	// Replace expected to replace the whole representation of a resource that is identified by id.
+	Replace(w http.ResponseWriter, r *http.Request)
+}
+
+type PatchController interface {
+	// Patch -- PATCH /{resourceID}
+	// Patch expected to partially modify the properties of a resource that is identified by id.
+	Patch(w http.ResponseWriter, r *http.Request)
+}
+
 type DeleteController interface {
 	// Delete -- DELETE /{resourceID}
 	// Delete is expected to make the resource unavailable one way or an another.
@@ -46,7 +73,22 @@ type WithNotFoundHandler interface {
 	NotFound(w http.ResponseWriter, r *http.Request)
 }
 
+type WithMethodNotAllowedHandler interface {
+	// MethodNotAllowed is expected to represent a response where the requested path exists,
+	// but the http method is not supported by the resource.
+	// The Allow header is already set with the supported methods when MethodNotAllowed is called.
+	MethodNotAllowed(w http.ResponseWriter, r *http.Request)
+}
+
 type WithInternalServerErrorHandler interface {
 	// InternalServerError is expected to represent an unexpected error occurrence in the request.
+	// The error that caused it can be retrieved from the request context with ErrorFromContext.
 	InternalServerError(w http.ResponseWriter, r *http.Request)
 }
+
+type WithErrorHandler interface {
+	// HandleError is expected to represent an error that occurred during the request to the requester.
+	// It receives the errors returned from ContextWithResource and the values recovered from panics.
+	// When implemented, it takes precedence over InternalServerError.
+	HandleError(w http.ResponseWriter, r *http.Request, err error)
+}
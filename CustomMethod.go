@@ -0,0 +1,117 @@
+package gorest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Scope tells what a custom method acts on.
+type Scope int
+
+const (
+	// CollectionScope custom methods act on the collection: POST /users:search
+	CollectionScope Scope = iota
+	// ResourceScope custom methods act on a resource of the collection: POST /users/42:archive
+	ResourceScope
+)
+
+// customMethods are the custom methods of a scope, by their verb.
+type customMethods map[string]*operations
+
+// HandleCustom registers a custom method in the AIP style, where the verb follows the path after a colon.
+// The handler is served as an OpCustom operation, so the Authorizer and the UseFor middlewares apply to it.
+// In ResourceScope, the verb is stripped from the resource id before ContextWithResource is called.
+//
+// example:
+//
+//	h.HandleCustom(gorest.CollectionScope, `search`, http.MethodPost, searchHandler)  // POST /users:search
+//	h.HandleCustom(gorest.ResourceScope, `archive`, http.MethodPost, archiveHandler) // POST /users/42:archive
+//
+// A path segment is only split at its last colon when the verb is registered,
+// so resource ids that contain a colon keep working.
+//
+// When the Handler is mounted on a multiplexer which is not a Handler,
+// the collection custom methods are registered on the multiplexer too, even when they are added after Mount.
+func (h *Handler) HandleCustom(scope Scope, verb, method string, handler http.Handler) {
+	if verb == `` || strings.ContainsAny(verb, `/:`) {
+		panic(fmt.Sprintf(`gorest: invalid custom method verb: %q`, verb))
+	}
+
+	methods := &h.customMethods.collection
+	if scope == ResourceScope {
+		methods = &h.customMethods.resource
+	}
+	if *methods == nil {
+		*methods = make(customMethods)
+	}
+	if (*methods)[verb] == nil {
+		(*methods)[verb] = &operations{}
+		if scope == CollectionScope && verb != batchVerb {
+			for _, m := range h.collectionMounts {
+				m.handle(h, verb)
+			}
+		}
+	}
+	(*methods)[verb].Set(method, OpCustom, handler)
+}
+
+// collectionMethod returns the custom method that a collection path like :search or /:search refers to.
+func (h *Handler) collectionMethod(path string) (operations, bool) {
+	path = strings.TrimPrefix(path, `/`)
+	if !strings.HasPrefix(path, `:`) {
+		return operations{}, false
+	}
+	return h.lookupCustomMethod(CollectionScope, path[1:])
+}
+
+// resourceMethod splits the custom method verb from the resource id, when the verb is registered.
+func (h *Handler) resourceMethod(resourceID string) (string, operations, bool) {
+	id, verb, ok := splitCustomMethod(resourceID)
+	if !ok {
+		return resourceID, operations{}, false
+	}
+	ops, ok := h.lookupCustomMethod(ResourceScope, verb)
+	if !ok {
+		return resourceID, operations{}, false
+	}
+	return id, ops, true
+}
+
+func (h *Handler) lookupCustomMethod(scope Scope, verb string) (operations, bool) {
+	methods := h.customMethods.collection
+	if scope == ResourceScope {
+		methods = h.customMethods.resource
+	}
+	if ops, ok := methods[verb]; ok {
+		return *ops, true
+	}
+	if scope == CollectionScope && verb == batchVerb && h.Batch != nil {
+		var ops operations
+		ops.Set(http.MethodPost, OpCustom, http.HandlerFunc(h.serveBatch))
+		return ops, true
+	}
+	return operations{}, false
+}
+
+// collectionVerbs returns the verbs of the collection custom methods.
+func (h *Handler) collectionVerbs() []string {
+	verbs := make([]string, 0, len(h.customMethods.collection)+1)
+	for verb := range h.customMethods.collection {
+		verbs = append(verbs, verb)
+	}
+	if _, ok := h.customMethods.collection[batchVerb]; !ok && h.Batch != nil {
+		verbs = append(verbs, batchVerb)
+	}
+	return verbs
+}
+
+// serveCustomMethod serves the request with the custom method that is registered for its http method.
+func (h *Handler) serveCustomMethod(w http.ResponseWriter, r *http.Request, ops operations) {
+	op, ok := ops.Lookup(r.Method)
+	if !ok {
+		h.unsupportedMethod(w, r, ops)
+		return
+	}
+	h.serveOperation(w, r, op)
+}
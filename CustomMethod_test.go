@@ -0,0 +1,133 @@
+package gorest_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestHandler_HandleCustom(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`resource ids`, func(t *testcase.T) interface{} { return &[]string{} })
+	var resourceIDs = func(t *testcase.T) []string { return *t.I(`resource ids`).(*[]string) }
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		h := gorest.NewHandler(StubController{
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				ptr := t.I(`resource ids`).(*[]string)
+				*ptr = append(*ptr, id)
+				return context.WithValue(ctx, `id`, id), true, nil
+			},
+			ShowFunc: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `show `+r.Context().Value(`id`).(string))
+			},
+		})
+		h.HandleCustom(gorest.CollectionScope, `search`, http.MethodPost, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, `search`)
+		}))
+		h.HandleCustom(gorest.ResourceScope, `archive`, http.MethodPost, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, `archive `+r.Context().Value(`id`).(string))
+		}))
+		return h
+	})
+	var handler = func(t *testcase.T) *gorest.Handler { return t.I(`handler`).(*gorest.Handler) }
+	var serve = func(t *testcase.T, h http.Handler, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	s.Test(`collection custom method`, func(t *testcase.T) {
+		resp := serve(t, handler(t), http.MethodPost, `/:search`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `search`, resp.Body.String())
+		require.Empty(t, resourceIDs(t))
+	})
+
+	s.Test(`resource custom method has the verb stripped from the resource id`, func(t *testcase.T) {
+		resp := serve(t, handler(t), http.MethodPost, `/42:archive`)
+		require.Equal(t, `archive 42`, resp.Body.String())
+		require.Equal(t, []string{`42`}, resourceIDs(t))
+	})
+
+	s.Test(`unsupported http method is rejected with 405`, func(t *testcase.T) {
+		resp := serve(t, handler(t), http.MethodGet, `/42:archive`)
+		require.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+		require.Equal(t, `OPTIONS, POST`, resp.Header().Get(`Allow`))
+	})
+
+	s.Test(`unregistered verb stays part of the resource id`, func(t *testcase.T) {
+		resp := serve(t, handler(t), http.MethodGet, `/urn:isbn`)
+		require.Equal(t, `show urn:isbn`, resp.Body.String())
+	})
+
+	s.Test(`custom methods are operations`, func(t *testcase.T) {
+		var ops []gorest.Operation
+		handler(t).UseFor(gorest.OpCustom, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				op, _ := gorest.OperationFromContext(r.Context())
+				ops = append(ops, op)
+				next.ServeHTTP(w, r)
+			})
+		})
+		serve(t, handler(t), http.MethodPost, `/:search`)
+		serve(t, handler(t), http.MethodPost, `/42:archive`)
+		require.Equal(t, []gorest.Operation{gorest.OpCustom, gorest.OpCustom}, ops)
+	})
+
+	s.Test(`mounted under a Handler`, func(t *testcase.T) {
+		parent := gorest.NewHandler(StubController{})
+		gorest.Mount(parent, `/users`, handler(t))
+		require.Equal(t, `search`, serve(t, parent, http.MethodPost, `/1/users:search`).Body.String())
+		require.Equal(t, `archive 42`, serve(t, parent, http.MethodPost, `/1/users/42:archive`).Body.String())
+	})
+
+	s.Test(`mounted on a ServeMux`, func(t *testcase.T) {
+		mux := http.NewServeMux()
+		gorest.Mount(mux, `/users`, handler(t))
+		require.Equal(t, `search`, serve(t, mux, http.MethodPost, `/users:search`).Body.String())
+		require.Equal(t, `archive 42`, serve(t, mux, http.MethodPost, `/users/42:archive`).Body.String())
+	})
+
+	s.Test(`registered after mounted on a ServeMux`, func(t *testcase.T) {
+		mux := http.NewServeMux()
+		gorest.Mount(mux, `/users`, handler(t))
+		handler(t).HandleCustom(gorest.CollectionScope, `export`, http.MethodPost, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, `export`)
+		}))
+		require.Equal(t, `export`, serve(t, mux, http.MethodPost, `/users:export`).Body.String())
+
+		require.Equal(t, http.StatusNotFound, serve(t, mux, http.MethodPost, `/users:batch`).Code)
+		handler(t).Batch = &gorest.BatchHandler{}
+		resp := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, `/users:batch`, strings.NewReader(`[{"method": "GET", "path": "/42"}]`))
+		r.Header.Set(`Content-Type`, `application/json`)
+		mux.ServeHTTP(resp, r)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `show 42`)
+	})
+
+	s.Test(`routes`, func(t *testcase.T) {
+		var paths []string
+		for _, route := range handler(t).Routes() {
+			if route.Operation == gorest.OpCustom {
+				paths = append(paths, route.Method+` `+route.Path)
+			}
+		}
+		require.Equal(t, []string{`POST /:search`, `POST /{id}:archive`}, paths)
+	})
+
+	s.Test(`invalid verb`, func(t *testcase.T) {
+		require.Panics(t, func() {
+			handler(t).HandleCustom(gorest.CollectionScope, `a/b`, http.MethodPost, http.NotFoundHandler())
+		})
+	})
+}
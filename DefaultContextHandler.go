@@ -1,7 +1,12 @@
 package gorest
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
+// DefaultContextHandler stores the resource id in the context under the ContextKey.
+// ParsingContextHandler is a type-safe alternative to it.
 type DefaultContextHandler struct{ ContextKey interface{} }
 
 func (d DefaultContextHandler) ContextWithResource(ctx context.Context, resourceID string) (context.Context, bool, error) {
@@ -11,3 +16,59 @@ func (d DefaultContextHandler) ContextWithResource(ctx context.Context, resource
 func (d DefaultContextHandler) GetResourceID(ctx context.Context) interface{} {
 	return ctx.Value(d.ContextKey)
 }
+
+// ParsingContextHandler parses the resource id into a typed value, and stores it in the context under the ContextKey.
+// When the resource id can't be parsed, the resource is reported as not found.
+//
+// example:
+//	gorest.ParsingContextHandler[int]{Parse: strconv.Atoi}
+type ParsingContextHandler[T any] struct {
+	ContextKey ContextKey[T]
+	Parse      func(resourceID string) (T, error)
+}
+
+func (d ParsingContextHandler[T]) ContextWithResource(ctx context.Context, resourceID string) (context.Context, bool, error) {
+	id, err := d.Parse(resourceID)
+	if err != nil {
+		return ctx, false, nil
+	}
+	return d.ContextKey.With(ctx, id), true, nil
+}
+
+// ValidResourceID reports whether the resource id can be parsed.
+func (d ParsingContextHandler[T]) ValidResourceID(resourceID string) bool {
+	_, err := d.Parse(resourceID)
+	return err == nil
+}
+
+func (d ParsingContextHandler[T]) GetResourceID(ctx context.Context) (T, bool) {
+	return d.ContextKey.Get(ctx)
+}
+
+var errInvalidUUID = errors.New(`gorest: invalid UUID`)
+
+// ParseUUID validates that the resource id is a UUID in its canonical textual representation,
+// and returns it in lowercase.
+func ParseUUID(resourceID string) (string, error) {
+	if len(resourceID) != 36 {
+		return ``, errInvalidUUID
+	}
+	bs := []byte(resourceID)
+	for i, c := range bs {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return ``, errInvalidUUID
+			}
+			continue
+		}
+		switch {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f':
+		case 'A' <= c && c <= 'F':
+			bs[i] = c + ('a' - 'A')
+		default:
+			return ``, errInvalidUUID
+		}
+	}
+	return string(bs), nil
+}
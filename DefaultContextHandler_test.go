@@ -0,0 +1,72 @@
+package gorest_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestParsingContextHandler(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	ch := gorest.ParsingContextHandler[int]{
+		ContextKey: gorest.NewContextKey[int](`id`),
+		Parse:      strconv.Atoi,
+	}
+
+	var serve = func(path string) *httptest.ResponseRecorder {
+		h := gorest.NewHandler(struct {
+			gorest.ContextHandler
+			gorest.ShowController
+		}{
+			ContextHandler: ch,
+			ShowController: gorest.AsShowController(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, _ := ch.GetResourceID(r.Context())
+				_, _ = fmt.Fprintf(w, `%d`, id+1)
+			})),
+		})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	s.Test(`parsable resource id is stored as a typed value`, func(t *testcase.T) {
+		resp := serve(`/41`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `42`, resp.Body.String())
+	})
+
+	s.Test(`not parsable resource id is reported as not found`, func(t *testcase.T) {
+		require.Equal(t, http.StatusNotFound, serve(`/forty-one`).Code)
+	})
+
+	s.Test(`missing resource id`, func(t *testcase.T) {
+		_, ok := ch.GetResourceID(context.Background())
+		require.False(t, ok)
+	})
+}
+
+func TestParseUUID(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`valid UUID`, func(t *testcase.T) {
+		id, err := gorest.ParseUUID(`123E4567-e89b-12d3-a456-426614174000`)
+		require.Nil(t, err)
+		require.Equal(t, `123e4567-e89b-12d3-a456-426614174000`, id)
+	})
+
+	s.Test(`invalid UUID`, func(t *testcase.T) {
+		for _, id := range []string{``, `42`, `123e4567e89b12d3a456426614174000`, `123e4567-e89b-12d3-a456-42661417400g`} {
+			_, err := gorest.ParseUUID(id)
+			require.Error(t, err, id)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package gorest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrorMapper turns an error into the status code and the message of the error response.
+type ErrorMapper interface {
+	MapError(err error) (code int, message string)
+}
+
+// ErrorMapperFunc is an adapter to use an ordinary function as an ErrorMapper.
+type ErrorMapperFunc func(err error) (code int, message string)
+
+func (fn ErrorMapperFunc) MapError(err error) (int, string) { return fn(err) }
+
+// DefaultErrorMapper maps the gorest sentinel errors to their http status codes:
+//
+//	ErrBadRequest             -> 400
+//	ErrForbidden              -> 403
+//	ErrNotFound               -> 404
+//	ErrNotAcceptable          -> 406
+//	ErrConflict               -> 409
+//	ErrIdempotencyKeyInFlight -> 409
+//	ErrGone                   -> 410
+//	ErrPreconditionFailed     -> 412
+//	ErrUnsupportedMediaType   -> 415
+//	ErrIdempotencyKeyReused   -> 422
+//	ErrPreconditionRequired   -> 428
+//	*QueryError               -> 400
+//	request body decode error -> 400
+//	*ValidationError          -> 422
+//
+// Every other error is mapped to 500.
+// The message is the status text, except for query, decode and validation errors, where it describes what is invalid.
+type DefaultErrorMapper struct{}
+
+func (DefaultErrorMapper) MapError(err error) (int, string) {
+	var qerr *QueryError
+	if errors.As(err, &qerr) {
+		return http.StatusBadRequest, qerr.Error()
+	}
+	var derr *decodeError
+	if errors.As(err, &derr) {
+		return http.StatusBadRequest, derr.Error()
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return http.StatusUnprocessableEntity, verr.Error()
+	}
+
+	code := http.StatusInternalServerError
+	for _, m := range defaultErrorCodes {
+		if errors.Is(err, m.err) {
+			code = m.code
+			break
+		}
+	}
+	return code, http.StatusText(code)
+}
+
+var defaultErrorCodes = []struct {
+	err  error
+	code int
+}{
+	{err: ErrBadRequest, code: http.StatusBadRequest},
+	{err: ErrForbidden, code: http.StatusForbidden},
+	{err: ErrNotFound, code: http.StatusNotFound},
+	{err: ErrNotAcceptable, code: http.StatusNotAcceptable},
+	{err: ErrConflict, code: http.StatusConflict},
+	{err: ErrIdempotencyKeyInFlight, code: http.StatusConflict},
+	{err: ErrGone, code: http.StatusGone},
+	{err: ErrPreconditionFailed, code: http.StatusPreconditionFailed},
+	{err: ErrUnsupportedMediaType, code: http.StatusUnsupportedMediaType},
+	{err: ErrIdempotencyKeyReused, code: http.StatusUnprocessableEntity},
+	{err: ErrPreconditionRequired, code: http.StatusPreconditionRequired},
+}
+
+// HandlerFunc is an adapter to use a function that returns an error as an http.Handler.
+// The returned error is replied with WriteError.
+//
+// example:
+//
+//	gorest.AsShowController(gorest.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		return gorest.ErrGone
+//	}))
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (fn HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := fn(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// WriteError replies to the request with the error,
+// using the ErrorMapper and the error handlers of the Handler that serves the request.
+// Outside of a Handler, the error is replied based on DefaultErrorMapper.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if h, ok := r.Context().Value(ctxKeyHandler{}).(*Handler); ok {
+		h.handleError(w, r, err)
+		return
+	}
+	code, msg := DefaultErrorMapper{}.MapError(err)
+	http.Error(w, msg, code)
+}
+
+type ctxKeyHandler struct{}
+
+func contextWithHandler(ctx context.Context, h *Handler) context.Context {
+	return context.WithValue(ctx, ctxKeyHandler{}, h)
+}
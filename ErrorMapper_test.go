@@ -0,0 +1,150 @@
+package gorest_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestDefaultErrorMapper(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	for err, code := range map[error]int{
+		gorest.ErrBadRequest:           http.StatusBadRequest,
+		gorest.ErrForbidden:            http.StatusForbidden,
+		gorest.ErrNotFound:             http.StatusNotFound,
+		gorest.ErrNotAcceptable:        http.StatusNotAcceptable,
+		gorest.ErrConflict:             http.StatusConflict,
+		gorest.ErrGone:                 http.StatusGone,
+		gorest.ErrUnsupportedMediaType: http.StatusUnsupportedMediaType,
+		errors.New(`boom`):             http.StatusInternalServerError,
+	} {
+		err, code := err, code
+		s.Test(err.Error(), func(t *testcase.T) {
+			gotCode, msg := gorest.DefaultErrorMapper{}.MapError(fmt.Errorf(`wrapped: %w`, err))
+			require.Equal(t, code, gotCode)
+			require.Equal(t, http.StatusText(code), msg)
+		})
+	}
+
+	s.Test(`validation error`, func(t *testcase.T) {
+		code, msg := gorest.DefaultErrorMapper{}.MapError(&gorest.ValidationError{Fields: map[string]string{
+			`name`: `is required`,
+			`age`:  `must be positive`,
+		}})
+		require.Equal(t, http.StatusUnprocessableEntity, code)
+		require.Equal(t, `gorest: validation failed: age: must be positive, name: is required`, msg)
+	})
+
+	s.Test(`request body decode error`, func(t *testcase.T) {
+		r := httptest.NewRequest(http.MethodPost, `/`, strings.NewReader(`{"age":"old"}`))
+		r.Header.Set(`Content-Type`, `application/json`)
+		var v struct {
+			Age int `json:"age"`
+		}
+		code, msg := gorest.DefaultErrorMapper{}.MapError(gorest.Decode(r, &v))
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, msg, `decoding request body as application/json`)
+		require.Contains(t, msg, `age`)
+	})
+}
+
+func TestWriteError(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		return gorest.NewHandler(struct {
+			gorest.ContextHandler
+			gorest.ShowController
+		}{
+			ContextHandler: StubController{},
+			ShowController: gorest.AsShowController(gorest.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				return t.I(`err`).(error)
+			})),
+		})
+	})
+	var handler = func(t *testcase.T) *gorest.Handler { return t.I(`handler`).(*gorest.Handler) }
+	var serve = func(t *testcase.T) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(t).ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/42`, nil))
+		return w
+	}
+
+	s.When(`the returned error is mapped to a client error`, func(s *testcase.Spec) {
+		s.Let(`err`, func(t *testcase.T) interface{} { return gorest.ErrGone })
+
+		s.Then(`the mapped status code is used`, func(t *testcase.T) {
+			resp := serve(t)
+			require.Equal(t, http.StatusGone, resp.Code)
+			require.Contains(t, resp.Body.String(), http.StatusText(http.StatusGone))
+		})
+	})
+
+	s.When(`the returned error is mapped to not found`, func(s *testcase.Spec) {
+		s.Let(`err`, func(t *testcase.T) interface{} { return gorest.ErrNotFound })
+
+		s.Then(`the not found handler is used`, func(t *testcase.T) {
+			handler(t).NotFound = NewTestControllerMockHandler(nil, http.StatusTeapot, `custom-not-found`)
+			resp := serve(t)
+			require.Equal(t, http.StatusTeapot, resp.Code)
+			require.Contains(t, resp.Body.String(), `custom-not-found`)
+		})
+	})
+
+	s.When(`the returned error is unexpected`, func(s *testcase.Spec) {
+		s.Let(`err`, func(t *testcase.T) interface{} { return errors.New(`boom`) })
+
+		s.Then(`the internal server error handler is used`, func(t *testcase.T) {
+			handler(t).InternalServerError = NewTestControllerMockHandler(nil, http.StatusServiceUnavailable, `custom-ise`)
+			resp := serve(t)
+			require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+			require.Contains(t, resp.Body.String(), `custom-ise`)
+		})
+	})
+
+	s.When(`the Handler has a custom error mapper`, func(s *testcase.Spec) {
+		s.Let(`err`, func(t *testcase.T) interface{} { return errors.New(`boom`) })
+
+		s.Then(`it is used to map the error`, func(t *testcase.T) {
+			handler(t).ErrorMapper = gorest.ErrorMapperFunc(func(err error) (int, string) {
+				return http.StatusTeapot, `teapot:` + err.Error()
+			})
+			resp := serve(t)
+			require.Equal(t, http.StatusTeapot, resp.Code)
+			require.Contains(t, resp.Body.String(), `teapot:boom`)
+		})
+	})
+
+	s.When(`the error is returned by the context handler`, func(s *testcase.Spec) {
+		s.Let(`err`, func(t *testcase.T) interface{} { return nil })
+
+		s.Then(`it is mapped as well`, func(t *testcase.T) {
+			handler(t).ContextHandler = ErrorContextHandler{Err: fmt.Errorf(`not yours: %w`, gorest.ErrForbidden)}
+			require.Equal(t, http.StatusForbidden, serve(t).Code)
+		})
+	})
+
+	s.Test(`a recovered panic is replied with 500 regardless of its value`, func(t *testcase.T) {
+		for _, cause := range []error{gorest.ErrForbidden, &gorest.ValidationError{Fields: map[string]string{`age`: `invalid`}}} {
+			cause := cause
+			h := gorest.NewHandler(StubController{ShowFunc: func(w http.ResponseWriter, r *http.Request) { panic(cause) }})
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/42`, nil))
+			require.Equal(t, http.StatusInternalServerError, w.Code)
+		}
+	})
+
+	s.Test(`outside of a Handler, the default error mapper is used`, func(t *testcase.T) {
+		w := httptest.NewRecorder()
+		gorest.WriteError(w, httptest.NewRequest(http.MethodGet, `/`, nil), gorest.ErrConflict)
+		require.Equal(t, http.StatusConflict, w.Code)
+	})
+}
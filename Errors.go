@@ -0,0 +1,80 @@
+package gorest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PanicError represents a panic that was recovered while the Handler served a request.
+type PanicError struct {
+	// Value is the value recovered from the panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine at the time of the recovery.
+	Stack []byte
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf(`gorest: panic: %v`, err.Value)
+}
+
+// Unwrap returns the recovered value when it is an error.
+func (err *PanicError) Unwrap() error {
+	if e, ok := err.Value.(error); ok {
+		return e
+	}
+	return nil
+}
+
+type ctxKeyError struct{}
+
+func contextWithError(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, ctxKeyError{}, err)
+}
+
+// ErrorFromContext returns the error that made the Handler reply with an error response.
+// It is available in the request context received by the NotFound and InternalServerError handlers.
+// A recovered panic is represented as a *PanicError.
+func ErrorFromContext(ctx context.Context) error {
+	err, _ := ctx.Value(ctxKeyError{}).(error)
+	return err
+}
+
+var (
+	// ErrBadRequest represents a request that can't be processed because it is malformed.
+	ErrBadRequest = errors.New(`gorest: bad request`)
+	// ErrForbidden represents a request that the requester is not allowed to make.
+	ErrForbidden = errors.New(`gorest: forbidden`)
+	// ErrNotFound represents a resource that doesn't exist.
+	ErrNotFound = errors.New(`gorest: not found`)
+	// ErrConflict represents a request that conflicts with the current state of the resource.
+	ErrConflict = errors.New(`gorest: conflict`)
+	// ErrGone represents a resource that existed, but it is no longer available.
+	ErrGone = errors.New(`gorest: gone`)
+	// ErrPreconditionFailed represents a conditional request whose precondition doesn't match the current state of the resource.
+	ErrPreconditionFailed = errors.New(`gorest: precondition failed`)
+	// ErrPreconditionRequired represents a request that must be conditional, but it has no precondition.
+	ErrPreconditionRequired = errors.New(`gorest: precondition required`)
+)
+
+// ValidationError represents a well-formed request that has semantically invalid content.
+type ValidationError struct {
+	// Fields maps the invalid field names to the reason of their rejection.
+	Fields map[string]string
+}
+
+func (err *ValidationError) Error() string {
+	names := make([]string, 0, len(err.Fields))
+	for name := range err.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reasons := make([]string, 0, len(names))
+	for _, name := range names {
+		reasons = append(reasons, name+`: `+err.Fields[name])
+	}
+	return `gorest: validation failed: ` + strings.Join(reasons, `, `)
+}
@@ -0,0 +1,392 @@
+package gorest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldMask selects a subset of the fields of a resource, like the sparse fieldset of ?fields=id,name,owner.email
+// The fields are referenced by their json name, and nested fields are referenced with a dot.
+type FieldMask struct {
+	Paths []FieldPath
+}
+
+// FieldPath is a path of a field mask: owner.email
+type FieldPath struct {
+	Fields []string
+	// Offset is the byte offset of the path in the fields query parameter.
+	Offset int
+}
+
+func (p FieldPath) String() string { return strings.Join(p.Fields, `.`) }
+
+func (m FieldMask) String() string {
+	paths := make([]string, 0, len(m.Paths))
+	for _, p := range m.Paths {
+		paths = append(paths, p.String())
+	}
+	return strings.Join(paths, `,`)
+}
+
+const fieldsParam = `fields`
+
+// ParseFieldMask parses a comma separated list of field paths.
+// The returned error is a *QueryError when the field mask is malformed.
+func ParseFieldMask(fields string) (FieldMask, error) {
+	var (
+		mask   FieldMask
+		offset int
+	)
+	for _, part := range strings.Split(fields, `,`) {
+		path := FieldPath{Offset: offset + strings.Index(part, strings.TrimSpace(part))}
+		offset += len(part) + 1
+
+		for _, name := range strings.Split(strings.TrimSpace(part), `.`) {
+			if !isFilterIdentifier(name) {
+				return FieldMask{}, &QueryError{Param: fieldsParam, Offset: path.Offset, Msg: fmt.Sprintf(`invalid field path %q`, strings.TrimSpace(part))}
+			}
+			path.Fields = append(path.Fields, name)
+		}
+		mask.Paths = append(mask.Paths, path)
+	}
+	return mask, nil
+}
+
+// Validate checks that every path of the mask references a field of the type.
+// Maps and interfaces are not validated, as their fields are only known at runtime.
+func (m FieldMask) Validate(typ reflect.Type) error {
+	for _, p := range m.Paths {
+		if !hasFieldPath(typ, p.Fields) {
+			return &QueryError{Param: fieldsParam, Offset: p.Offset, Msg: fmt.Sprintf(`unknown field %q`, p.String())}
+		}
+	}
+	return nil
+}
+
+// Apply returns the JSON representation of v that only contains the fields selected by the mask.
+// Lists are shaped element by element.
+// The returned error is a *QueryError when the mask references an unknown field.
+func (m FieldMask) Apply(v interface{}) (interface{}, error) {
+	if err := m.Validate(reflect.TypeOf(v)); err != nil {
+		return nil, err
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSONDocument(bs)
+	if err != nil {
+		return nil, err
+	}
+	return m.tree().prune(doc), nil
+}
+
+// Copy copies the fields selected by the mask from src to dst.
+// Both of them must be pointers to the same struct type.
+// It lets an Update operation touch only the fields that the requester declared.
+func (m FieldMask) Copy(dst, src interface{}) error {
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Ptr || sv.Kind() != reflect.Ptr || dv.Type() != sv.Type() {
+		return fmt.Errorf(`gorest: FieldMask.Copy requires pointers of the same type, got %T and %T`, dst, src)
+	}
+	if err := m.Validate(dv.Type()); err != nil {
+		return err
+	}
+	for _, p := range m.Paths {
+		copyFieldPath(dv.Elem(), sv.Elem(), p.Fields)
+	}
+	return nil
+}
+
+func hasFieldPath(typ reflect.Type, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	if typ == nil {
+		return true
+	}
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Map, reflect.Interface:
+		return true
+	case reflect.Struct:
+		sf, ok := jsonStructField(typ, names[0])
+		return ok && hasFieldPath(sf.Type, names[1:])
+	default:
+		return false
+	}
+}
+
+// jsonStructField finds a struct field by its json name, including the fields of embedded structs.
+func jsonStructField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := strings.Split(sf.Tag.Get(`json`), `,`)[0]
+		if tag == `-` {
+			continue
+		}
+		if sf.Anonymous && tag == `` {
+			et := sf.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				if esf, ok := jsonStructField(et, name); ok {
+					esf.Index = append([]int{i}, esf.Index...)
+					return esf, true
+				}
+			}
+			continue
+		}
+		if sf.PkgPath != `` { // unexported
+			continue
+		}
+		if tag == name || (tag == `` && sf.Name == name) {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func copyFieldPath(dst, src reflect.Value, names []string) {
+	if len(names) == 0 {
+		dst.Set(src)
+		return
+	}
+	if src.Kind() == reflect.Ptr {
+		if src.IsNil() {
+			src = reflect.Zero(src.Type().Elem())
+		} else {
+			src = src.Elem()
+		}
+	}
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		dst = dst.Elem()
+	}
+	if dst.Kind() != reflect.Struct {
+		dst.Set(src)
+		return
+	}
+
+	sf, _ := jsonStructField(dst.Type(), names[0])
+	df, ok := fieldByIndexAlloc(dst, sf.Index)
+	if !ok {
+		return
+	}
+	sfv, err := src.FieldByIndexErr(sf.Index)
+	if err != nil { // nil embedded struct pointer
+		sfv = reflect.Zero(sf.Type)
+	}
+	copyFieldPath(df, sfv, names[1:])
+}
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex, but it allocates the nil embedded struct pointers.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if 0 < i && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, v.IsValid()
+}
+
+// fieldMaskTree is the field mask as a tree of field names.
+// A nil subtree selects the whole field.
+type fieldMaskTree map[string]fieldMaskTree
+
+func (m FieldMask) tree() fieldMaskTree {
+	root := make(fieldMaskTree)
+	for _, p := range m.Paths {
+		node := root
+		for i, name := range p.Fields {
+			child, ok := node[name]
+			if ok && child == nil {
+				break
+			}
+			if i == len(p.Fields)-1 {
+				node[name] = nil
+				break
+			}
+			if !ok {
+				child = make(fieldMaskTree)
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+func (t fieldMaskTree) prune(doc interface{}) interface{} {
+	if t == nil {
+		return doc
+	}
+	switch doc := doc.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(doc))
+		for i, elem := range doc {
+			out[i] = t.prune(elem)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for name, child := range t {
+			if v, ok := doc[name]; ok {
+				out[name] = child.prune(v)
+			}
+		}
+		return out
+	default:
+		return doc
+	}
+}
+
+func decodeJSONDocument(bs []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	var doc interface{}
+	return doc, dec.Decode(&doc)
+}
+
+func isJSONMediaType(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == `application/json` || strings.HasSuffix(mt, `+json`)
+}
+
+type ctxKeyFieldMask struct{}
+
+// fieldMaskState is the field mask of a request.
+type fieldMaskState struct {
+	mask FieldMask
+	// shape tells that the response has to be shaped by the mask.
+	shape bool
+	// shaped tells that Encode already shaped the response.
+	shaped bool
+}
+
+func fieldMaskStateFromContext(ctx context.Context) (*fieldMaskState, bool) {
+	state, ok := ctx.Value(ctxKeyFieldMask{}).(*fieldMaskState)
+	return state, ok
+}
+
+// FieldMaskFromContext returns the field mask of the request, when the Handler has FieldMasks enabled
+// and the request has the fields query parameter.
+// For List and Show it is the sparse fieldset of the response,
+// and for Update it is the set of fields that the request is meant to change.
+func FieldMaskFromContext(ctx context.Context) (FieldMask, bool) {
+	state, ok := fieldMaskStateFromContext(ctx)
+	if !ok {
+		return FieldMask{}, false
+	}
+	return state.mask, true
+}
+
+// withFieldMask parses the field mask of the request for the operations that support it.
+func (h *Handler) withFieldMask(kind Operation, next http.Handler) http.Handler {
+	if !h.FieldMasks || kind&(OpList|OpShow|OpUpdate) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !query.Has(fieldsParam) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mask, err := ParseFieldMask(query.Get(fieldsParam))
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		state := &fieldMaskState{mask: mask, shape: kind != OpUpdate}
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeyFieldMask{}, state))
+		if !state.shape {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fw := &fieldMaskResponseWriter{ResponseWriter: w, state: state}
+		next.ServeHTTP(fw, r)
+		fw.finish(r)
+	})
+}
+
+// fieldMaskResponseWriter buffers the response, so a JSON body that wasn't shaped by Encode can be shaped by the mask.
+type fieldMaskResponseWriter struct {
+	http.ResponseWriter
+	state *fieldMaskState
+	code  int
+	body  bytes.Buffer
+}
+
+func (w *fieldMaskResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *fieldMaskResponseWriter) Write(bs []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(bs)
+}
+
+func (w *fieldMaskResponseWriter) finish(r *http.Request) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	body := w.body.Bytes()
+	if !w.state.shaped && w.code/100 == 2 && isJSONMediaType(w.Header().Get(`Content-Type`)) && 0 < len(body) {
+		shaped, err := w.shape(body)
+		if err != nil {
+			w.Header().Del(`Content-Length`)
+			WriteError(w.ResponseWriter, r, err)
+			return
+		}
+		body = shaped
+		if w.Header().Get(`Content-Length`) != `` {
+			w.Header().Set(`Content-Length`, strconv.Itoa(len(body)))
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// shape prunes the JSON body to the mask.
+// The fields missing from the body are left out without an error,
+// as the body can't tell an unknown field from one that was omitted, like an omitempty field.
+func (w *fieldMaskResponseWriter) shape(body []byte) ([]byte, error) {
+	doc, err := decodeJSONDocument(body)
+	if err != nil {
+		return body, nil
+	}
+	shaped, err := json.Marshal(w.state.mask.tree().prune(doc))
+	if err != nil {
+		return nil, err
+	}
+	return append(shaped, '\n'), nil
+}
@@ -0,0 +1,228 @@
+package gorest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+type Project struct {
+	ID    int           `json:"id"`
+	Name  string        `json:"name"`
+	Owner *ProjectOwner `json:"owner,omitempty"`
+	Tags  []string      `json:"tags"`
+}
+
+type ProjectOwner struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestParseFieldMask(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`paths`, func(t *testcase.T) {
+		mask, err := gorest.ParseFieldMask(`id, owner.email`)
+		require.Nil(t, err)
+		require.Equal(t, gorest.FieldMask{Paths: []gorest.FieldPath{
+			{Fields: []string{`id`}, Offset: 0},
+			{Fields: []string{`owner`, `email`}, Offset: 4},
+		}}, mask)
+		require.Equal(t, `id,owner.email`, mask.String())
+	})
+
+	s.Test(`malformed path`, func(t *testcase.T) {
+		_, err := gorest.ParseFieldMask(`id,owner..email`)
+		require.Equal(t, &gorest.QueryError{Param: `fields`, Offset: 3, Msg: `invalid field path "owner..email"`}, err)
+		require.True(t, errors.Is(err, gorest.ErrBadRequest))
+	})
+}
+
+func TestFieldMask_Apply(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	project := Project{ID: 1, Name: `gorest`, Owner: &ProjectOwner{Name: `Jane`, Email: `jane@example.com`}, Tags: []string{`go`}}
+
+	s.Test(`struct`, func(t *testcase.T) {
+		mask, _ := gorest.ParseFieldMask(`id,owner.email`)
+		v, err := mask.Apply(project)
+		require.Nil(t, err)
+		require.Equal(t, map[string]interface{}{
+			`id`:    json.Number(`1`),
+			`owner`: map[string]interface{}{`email`: `jane@example.com`},
+		}, v)
+	})
+
+	s.Test(`list`, func(t *testcase.T) {
+		mask, _ := gorest.ParseFieldMask(`name,owner`)
+		v, err := mask.Apply([]Project{project, {ID: 2, Name: `other`}})
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{
+			map[string]interface{}{`name`: `gorest`, `owner`: map[string]interface{}{`name`: `Jane`, `email`: `jane@example.com`}},
+			map[string]interface{}{`name`: `other`},
+		}, v)
+	})
+
+	s.Test(`unknown field`, func(t *testcase.T) {
+		mask, _ := gorest.ParseFieldMask(`id,owner.phone`)
+		_, err := mask.Apply(&project)
+		require.Equal(t, &gorest.QueryError{Param: `fields`, Offset: 3, Msg: `unknown field "owner.phone"`}, err)
+	})
+}
+
+func TestFieldMask_Copy(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`only the masked fields are copied`, func(t *testcase.T) {
+		dst := Project{ID: 1, Name: `gorest`, Owner: &ProjectOwner{Name: `Jane`, Email: `jane@example.com`}}
+		src := Project{Name: `renamed`, Owner: &ProjectOwner{Email: `jane@example.org`}, Tags: []string{`go`}}
+		mask, _ := gorest.ParseFieldMask(`owner.email,tags`)
+		require.Nil(t, mask.Copy(&dst, &src))
+		require.Equal(t, Project{ID: 1, Name: `gorest`, Owner: &ProjectOwner{Name: `Jane`, Email: `jane@example.org`}, Tags: []string{`go`}}, dst)
+	})
+
+	s.Test(`the masked fields are cleared when the source doesn't have them`, func(t *testcase.T) {
+		dst := Project{ID: 1, Name: `gorest`, Owner: &ProjectOwner{Name: `Jane`}}
+		mask, _ := gorest.ParseFieldMask(`name,owner.name`)
+		require.Nil(t, mask.Copy(&dst, &Project{}))
+		require.Equal(t, Project{ID: 1, Owner: &ProjectOwner{}}, dst)
+	})
+
+	s.Test(`unknown field`, func(t *testcase.T) {
+		mask, _ := gorest.ParseFieldMask(`owner.phone`)
+		require.Error(t, mask.Copy(&Project{}, &Project{}))
+	})
+}
+
+type ProjectRepository struct {
+	Projects map[int]Project
+}
+
+func (repo *ProjectRepository) FindAll(ctx context.Context) ([]Project, error) {
+	var projects []Project
+	for id := 1; id <= len(repo.Projects); id++ {
+		projects = append(projects, repo.Projects[id])
+	}
+	return projects, nil
+}
+
+func (repo *ProjectRepository) Create(ctx context.Context, p *Project) error { return nil }
+
+func (repo *ProjectRepository) FindByID(ctx context.Context, id int) (Project, bool, error) {
+	p, ok := repo.Projects[id]
+	return p, ok, nil
+}
+
+func (repo *ProjectRepository) Update(ctx context.Context, id int, p *Project) error {
+	repo.Projects[id] = *p
+	return nil
+}
+
+func (repo *ProjectRepository) DeleteByID(ctx context.Context, id int) error { return nil }
+
+func TestHandler_FieldMasks(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`repository`, func(t *testcase.T) interface{} {
+		return &ProjectRepository{Projects: map[int]Project{
+			1: {ID: 1, Name: `gorest`, Owner: &ProjectOwner{Name: `Jane`, Email: `jane@example.com`}},
+			2: {ID: 2, Name: `testcase`},
+		}}
+	})
+	var repository = func(t *testcase.T) *ProjectRepository { return t.I(`repository`).(*ProjectRepository) }
+	s.Let(`controller`, func(t *testcase.T) interface{} {
+		return gorest.ResourceController[Project, int]{Repository: repository(t), ParseID: strconv.Atoi}
+	})
+	s.Let(`enabled`, func(t *testcase.T) interface{} { return true })
+	var serve = func(t *testcase.T, method, target string, body io.Reader) *httptest.ResponseRecorder {
+		h := gorest.NewHandler(t.I(`controller`))
+		h.FieldMasks = t.I(`enabled`).(bool)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, body))
+		return w
+	}
+
+	s.Test(`Show response is shaped`, func(t *testcase.T) {
+		resp := serve(t, http.MethodGet, `/1?fields=name,owner.email`, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.JSONEq(t, `{"name":"gorest","owner":{"email":"jane@example.com"}}`, resp.Body.String())
+	})
+
+	s.Test(`List response is shaped`, func(t *testcase.T) {
+		resp := serve(t, http.MethodGet, `/?fields=id`, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.JSONEq(t, `[{"id":1},{"id":2}]`, resp.Body.String())
+	})
+
+	s.Test(`unknown field is rejected`, func(t *testcase.T) {
+		resp := serve(t, http.MethodGet, `/1?fields=name,secret`, nil)
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), `unknown field "secret"`)
+	})
+
+	s.Test(`Update changes only the masked fields`, func(t *testcase.T) {
+		resp := serve(t, http.MethodPatch, `/1?fields=owner.email`, strings.NewReader(`{"name":"ignored","owner":{"email":"jane@example.org"}}`))
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, Project{ID: 1, Name: `gorest`, Owner: &ProjectOwner{Name: `Jane`, Email: `jane@example.org`}}, repository(t).Projects[1])
+	})
+
+	s.When(`the controller writes the JSON itself`, func(s *testcase.Spec) {
+		s.Let(`controller`, func(t *testcase.T) interface{} {
+			return gorest.AsShowController(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(`Content-Type`, `application/json`)
+				_, _ = io.WriteString(w, `{"id":1,"name":"gorest","owner":null}`)
+			}))
+		})
+
+		s.Then(`the encoded JSON is shaped`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/1?fields=id,owner.email`, nil)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.JSONEq(t, `{"id":1,"owner":null}`, resp.Body.String())
+		})
+
+		s.Then(`the fields missing from the JSON are left out`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/1?fields=id,nope`, nil)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.JSONEq(t, `{"id":1}`, resp.Body.String())
+		})
+	})
+
+	s.When(`the controller writes a JSON without an omitempty field`, func(s *testcase.Spec) {
+		type User struct {
+			ID   int    `json:"id"`
+			Name string `json:"name,omitempty"`
+		}
+		s.Let(`controller`, func(t *testcase.T) interface{} {
+			return gorest.AsShowController(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(`Content-Type`, `application/json`)
+				_ = json.NewEncoder(w).Encode(User{ID: 1})
+			}))
+		})
+
+		s.Then(`the omitted field is not rejected`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/1?fields=name`, nil)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.JSONEq(t, `{}`, resp.Body.String())
+		})
+	})
+
+	s.When(`field masks are not enabled`, func(s *testcase.Spec) {
+		s.Let(`enabled`, func(t *testcase.T) interface{} { return false })
+
+		s.Then(`the response is not shaped`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/2?fields=id`, nil)
+			require.JSONEq(t, `{"id":2,"name":"testcase","tags":null}`, resp.Body.String())
+		})
+	})
+}
@@ -2,78 +2,244 @@ package gorest
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/url"
+	"runtime/debug"
+	"sort"
 	"strings"
 )
 
 // NewHandler builds a new *Handler instance and try to setup the handler parameters with the passed controller.
 func NewHandler(ctrl interface{}) *Handler {
-	h := &Handler{}
+	h := &Handler{controller: ctrl}
 	if i, ok := ctrl.(ContextHandler); ok {
 		h.ContextHandler = i
 	}
 	if i, ok := ctrl.(CreateController); ok {
-		h.operations.collection.Set(http.MethodPost, http.HandlerFunc(i.Create))
+		h.operations.collection.Set(http.MethodPost, OpCreate, http.HandlerFunc(i.Create))
 	}
 	if i, ok := ctrl.(ListController); ok {
-		h.operations.collection.Set(http.MethodGet, http.HandlerFunc(i.List))
+		h.operations.collection.Set(http.MethodGet, OpList, http.HandlerFunc(i.List))
+		h.operations.collection.Set(http.MethodHead, OpList, headHandler{Handler: http.HandlerFunc(i.List)})
+	}
+	if i, ok := ctrl.(PaginatedListController); ok {
+		list := paginatedList{handler: h, controller: i}
+		h.operations.collection.Set(http.MethodGet, OpList, list)
+		h.operations.collection.Set(http.MethodHead, OpList, headHandler{Handler: list})
+	}
+	if i, ok := ctrl.(ListHeadController); ok {
+		h.operations.collection.Set(http.MethodHead, OpList, http.HandlerFunc(i.ListHead))
+	}
+	if i, ok := ctrl.(WithListQuerySchema); ok {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			if op, ok := h.operations.collection.Lookup(method); ok {
+				h.operations.collection.Set(method, OpList, listQueryHandler{Handler: op.handler, schema: i.ListQuerySchema()})
+			}
+		}
 	}
 	if i, ok := ctrl.(ShowController); ok {
-		h.operations.resource.Set(http.MethodGet, http.HandlerFunc(i.Show))
+		h.operations.resource.Set(http.MethodGet, OpShow, http.HandlerFunc(i.Show))
+		h.operations.resource.Set(http.MethodHead, OpShow, headHandler{Handler: http.HandlerFunc(i.Show)})
+	}
+	if i, ok := ctrl.(HeadController); ok {
+		h.operations.resource.Set(http.MethodHead, OpShow, http.HandlerFunc(i.Head))
 	}
 	if i, ok := ctrl.(UpdateController); ok {
-		h.operations.resource.Set(http.MethodPut, http.HandlerFunc(i.Update))
-		h.operations.resource.Set(http.MethodPatch, http.HandlerFunc(i.Update))
+		h.operations.resource.Set(http.MethodPut, OpUpdate, http.HandlerFunc(i.Update))
+		h.operations.resource.Set(http.MethodPatch, OpUpdate, http.HandlerFunc(i.Update))
+	}
+	if i, ok := ctrl.(ReplaceController); ok {
+		h.operations.resource.Set(http.MethodPut, OpUpdate, http.HandlerFunc(i.Replace))
+	}
+	if i, ok := ctrl.(PatchController); ok {
+		h.operations.resource.Set(http.MethodPatch, OpUpdate, http.HandlerFunc(i.Patch))
 	}
 	if i, ok := ctrl.(DeleteController); ok {
-		h.operations.resource.Set(http.MethodDelete, http.HandlerFunc(i.Delete))
+		h.operations.resource.Set(http.MethodDelete, OpDelete, http.HandlerFunc(i.Delete))
 	}
 	if i, ok := ctrl.(WithNotFoundHandler); ok {
 		h.NotFound = http.HandlerFunc(i.NotFound)
 	}
+	if i, ok := ctrl.(WithMethodNotAllowedHandler); ok {
+		h.MethodNotAllowed = http.HandlerFunc(i.MethodNotAllowed)
+	}
 	if i, ok := ctrl.(WithInternalServerErrorHandler); ok {
 		h.InternalServerError = http.HandlerFunc(i.InternalServerError)
 	}
+	if i, ok := ctrl.(WithErrorHandler); ok {
+		h.ErrorHandler = i.HandleError
+	}
+	if i, ok := ctrl.(ErrorMapper); ok {
+		h.ErrorMapper = i
+	}
+	if i, ok := ctrl.(Authorizer); ok {
+		h.Authorizer = i
+	}
 	return h
 }
 
 type Handler struct {
 	ContextHandler      ContextHandler
 	NotFound            http.Handler
+	MethodNotAllowed    http.Handler
 	InternalServerError http.Handler
-	operations          struct {
+	// ErrorHandler receives the errors that occur during the request, and takes precedence over InternalServerError.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+	// ErrorMapper maps the errors to status codes when there is no ErrorHandler.
+	// Errors mapped to 404 are replied with NotFound, and errors mapped to 500 with InternalServerError.
+	// When it is nil, DefaultErrorMapper is used.
+	// Recovered panics are not mapped, they are always replied with InternalServerError.
+	ErrorMapper ErrorMapper
+	// CORS is the cross-origin resource sharing policy of the Handler.
+	// When a Handler is mounted under another Handler, the policy of the most inner Handler wins.
+	CORS *CORS
+	// Codecs is the codec registry used by Encode and Decode during the requests served by the Handler.
+	// When it is nil, the codecs of the outer Handler are used, or DefaultCodecs if none of them has codecs.
+	Codecs *Codecs
+	// ProblemDetails makes the Handler reply its errors as RFC 9457 problem details documents,
+	// when the requester doesn't prefer plain text.
+	// It applies to the default error responses, and to the errors replied with WriteError.
+	// When it is nil, the setting of the outer Handler is used.
+	ProblemDetails *ProblemDetails
+	// PanicHandler receives the panics recovered while the Handler served a request, with their stack trace.
+	// When it is nil, the PanicHandler of the outer Handler is used.
+	PanicHandler func(r *http.Request, cause interface{}, stack []byte)
+	// Authorizer guards every operation of the Handler, including the custom operations registered with Handle.
+	Authorizer Authorizer
+	// Pagination is used to serve the List operation of a PaginatedListController.
+	// When it is nil, the zero Pagination is used.
+	Pagination *Pagination
+	// FieldMasks enables the fields query parameter for the List, Show and Update operations.
+	// The JSON responses of List and Show are shaped to the requested fields,
+	// and Update can learn the fields to change with FieldMaskFromContext.
+	FieldMasks bool
+	// RequireIfMatch makes Update and Delete reject the requests without an If-Match header with 428 Precondition Required,
+	// when the resource of the request is Versioned.
+	RequireIfMatch bool
+	// ListETags makes the Handler buffer the GET responses of the List operation to compute a weak ETag from their body,
+	// so the requests with a matching If-None-Match header are answered with 304 Not Modified.
+	ListETags bool
+	// Idempotency makes the Create operation honour the Idempotency-Key request header,
+	// by recording its responses in the store, and replaying them for the retries.
+	// See Idempotent for the details.
+	Idempotency IdempotencyStore
+	// IdempotencyScope returns the scope of the idempotency keys of a request, like the id of the authenticated principal,
+	// so a requester can't replay the responses of another requester.
+	// When it is nil, the keys are shared by every requester.
+	IdempotencyScope func(r *http.Request) string
+	// Batch enables the batch collection custom method of the Handler: POST /:batch
+	// When the Handler of the BatchHandler is nil, the items are served by this Handler.
+	// A custom method registered with HandleCustom for the batch verb takes precedence over it.
+	Batch      *BatchHandler
+	operations struct {
 		collection operations
 		resource   operations
 	}
-	handlers handlers
+	customMethods struct {
+		collection customMethods
+		resource   customMethods
+	}
+	// collectionMounts are the mounts of the Handler on multiplexers which are not a Handler.
+	collectionMounts []collectionMount
+	// collectionHandlers are the collection level routes registered with HandleCollection.
+	collectionHandlers handlers
+	handlers           handlers
+	controller         interface{}
+
+	middlewares          []Middleware
+	operationMiddlewares []operationMiddleware
+	// chain is the route method wrapped in the middlewares registered with Use.
+	chain http.Handler
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tw := newTrackingResponseWriter(w)
+	w = tw
 	defer func() {
 		if cause := recover(); cause != nil {
-			h.internalServerError(w, r)
+			h.recover(tw, r, cause)
 		}
 	}()
 
+	r = r.WithContext(contextWithHandler(r.Context(), h))
+	if h.PanicHandler != nil {
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeyPanicHandler{}, h.PanicHandler))
+	}
+
+	if h.CORS != nil {
+		r = h.CORS.apply(w, r)
+	}
+	if h.Codecs != nil {
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeyCodecs{}, h.Codecs))
+	}
+	if h.ProblemDetails != nil {
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeyProblemDetails{}, h.ProblemDetails))
+	}
+
+	if h.chain != nil {
+		h.chain.ServeHTTP(w, r)
+		return
+	}
+	h.route(w, r)
+}
+
+// route dispatches the request to the operation or the nested handler that serves it.
+func (h *Handler) route(w http.ResponseWriter, r *http.Request) {
 	var method = r.Method
 
+	if ops, ok := h.collectionMethod(r.URL.Path); ok {
+		h.serveCustomMethod(w, r, ops)
+		return
+	}
+
 	switch r.URL.Path {
 	case `/`, ``:
-		ch, ok := h.operations.collection.Lookup(method)
+		op, ok := h.operations.collection.Lookup(method)
 		if !ok {
-			h.notFound(w, r)
+			h.unsupportedMethod(w, r, h.operations.collection)
 			return
 		}
 
-		ch.ServeHTTP(w, r)
+		h.serveOperation(w, r, op)
 
 	default: // dynamic path
+		if h.collectionHandlers.hasHandlerWithPrefixThatMatch(r.URL.Path) {
+			h.collectionHandlers.ServeHTTP(w, r)
+			return
+		}
+
 		ctx := r.Context()
 		r, resourceID := UnshiftPathParamFromRequest(r)
+		var customMethod operations
+		var isCustomMethod bool
+		if r.URL.Path == `/` {
+			resourceID, customMethod, isCustomMethod = h.resourceMethod(resourceID)
+		}
+
+		// preflight requests are sent without credentials, so they are answered before the resource lookup,
+		// and the preflight requests of the mounted sub collections are passed on without it.
+		if r.URL.Path == `/` && isPreflightRequest(r) {
+			ops := h.operations.resource
+			if isCustomMethod {
+				ops = customMethod
+			}
+			if _, ok := ops.Lookup(method); !ok && !ops.IsEmpty() {
+				h.options(w, r, ops)
+				return
+			}
+		}
+		if isPreflightRequest(r) && h.handlers.hasHandlerWithPrefixThatMatch(r.URL.Path) {
+			if _, ok := h.handlers.handler(r).(*mountedHandler); ok {
+				h.handlers.ServeHTTP(w, r)
+				return
+			}
+		}
+
 		ctx, found, err := h.handleResourceID(ctx, resourceID)
 
 		if err != nil {
-			h.internalServerError(w, r)
+			h.handleError(w, r, err)
 			return
 		}
 
@@ -84,30 +250,105 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		r = r.WithContext(ctx)
 
+		if isCustomMethod {
+			h.serveCustomMethod(w, r, customMethod)
+			return
+		}
+
 		if h.handlers.hasHandlerWithPrefixThatMatch(r.URL.Path) {
 			h.handlers.ServeHTTP(w, r)
 			return
 		}
 
-		rh, ok := h.operations.resource.Lookup(method)
+		op, ok := h.operations.resource.Lookup(method)
 		if !ok && h.handlers.hasRootHandler {
 			h.handlers.ServeHTTP(w, r)
 			return
 		}
 		if !ok {
-			h.notFound(w, r)
+			h.unsupportedMethod(w, r, h.operations.resource)
 			return
 		}
 
-		rh.ServeHTTP(w, r)
+		h.serveOperation(w, r, op)
 
 	}
 }
 
 func (h *Handler) Handle(pattern string, handler http.Handler) {
+	if _, ok := handler.(*mountedHandler); !ok {
+		handler = customOperation{handler: handler, owner: h}
+	}
 	h.handlers.Handle(pattern, handler)
 }
 
+type ctxKeyPanicHandler struct{}
+
+// recover handles a panic that occurred during the request.
+// http.ErrAbortHandler is panicked further, as it is meant to abort the response in net/http.
+// When the response was already started, the error response can't be written anymore,
+// so the response is aborted with http.ErrAbortHandler after the PanicHandler is notified.
+func (h *Handler) recover(w *trackingResponseWriter, r *http.Request, cause interface{}) {
+	if cause == http.ErrAbortHandler {
+		panic(cause)
+	}
+
+	stack := debug.Stack()
+	if ph, ok := r.Context().Value(ctxKeyPanicHandler{}).(func(*http.Request, interface{}, []byte)); ok {
+		ph(r, cause, stack)
+	}
+
+	if w.wroteHeader {
+		panic(http.ErrAbortHandler)
+	}
+
+	h.handleError(w, r, &PanicError{Value: cause, Stack: stack})
+}
+
+// handleError replies to the request with the error.
+// The error is passed to the ErrorHandler when it is set,
+// otherwise the ErrorMapper tells how the error should be replied.
+func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	r = r.WithContext(contextWithError(r.Context(), err))
+
+	if h.ErrorHandler != nil {
+		defer func() {
+			if cause := recover(); cause != nil {
+				h.defaultInternalServerError(w, r)
+			}
+		}()
+		h.ErrorHandler(w, r, err)
+		return
+	}
+
+	// a panic is a failure of the server, regardless of the recovered value.
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		h.internalServerError(w, r)
+		return
+	}
+
+	code, msg := h.errorMapper().MapError(err)
+	switch code {
+	case http.StatusNotFound:
+		h.notFound(w, r)
+	case http.StatusInternalServerError:
+		h.internalServerError(w, r)
+	default:
+		if msg == http.StatusText(code) {
+			msg = ``
+		}
+		httpError(w, r, code, msg)
+	}
+}
+
+func (h *Handler) errorMapper() ErrorMapper {
+	if h.ErrorMapper == nil {
+		return DefaultErrorMapper{}
+	}
+	return h.ErrorMapper
+}
+
 func (h *Handler) internalServerError(w http.ResponseWriter, r *http.Request) {
 	if h.InternalServerError == nil {
 		h.defaultInternalServerError(w, r)
@@ -122,61 +363,187 @@ func (h *Handler) internalServerError(w http.ResponseWriter, r *http.Request) {
 	h.InternalServerError.ServeHTTP(w, r)
 }
 
-func (h *Handler) defaultInternalServerError(w http.ResponseWriter, _ *http.Request) {
-	const code = http.StatusInternalServerError
-	http.Error(w, http.StatusText(code), code)
+func (h *Handler) defaultInternalServerError(w http.ResponseWriter, r *http.Request) {
+	httpError(w, r, http.StatusInternalServerError, ``)
 }
 
 func (h *Handler) notFound(w http.ResponseWriter, r *http.Request) {
 	if h.NotFound == nil {
-		http.NotFound(w, r)
+		httpError(w, r, http.StatusNotFound, ``)
 		return
 	}
 
 	h.NotFound.ServeHTTP(w, r)
 }
 
+// unsupportedMethod replies with 405 when the path has at least one operation registered,
+// otherwise the path is considered as non existing, and 404 is used.
+// OPTIONS requests are answered based on the registered operations.
+func (h *Handler) unsupportedMethod(w http.ResponseWriter, r *http.Request, ops operations) {
+	if ops.IsEmpty() {
+		h.notFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodOptions {
+		h.options(w, r, ops)
+		return
+	}
+
+	h.methodNotAllowed(w, r, ops)
+}
+
+func (h *Handler) options(w http.ResponseWriter, r *http.Request, ops operations) {
+	methods := allowedMethods(ops)
+	w.Header().Set(`Allow`, strings.Join(methods, `, `))
+
+	if policy, ok := corsFromContext(r.Context()); ok && isPreflightRequest(r) {
+		policy.preflight(w, r, methods)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) methodNotAllowed(w http.ResponseWriter, r *http.Request, ops operations) {
+	w.Header().Set(`Allow`, strings.Join(allowedMethods(ops), `, `))
+
+	if h.MethodNotAllowed == nil {
+		httpError(w, r, http.StatusMethodNotAllowed, ``)
+		return
+	}
+
+	h.MethodNotAllowed.ServeHTTP(w, r)
+}
+
 func (h *Handler) handleResourceID(ctx context.Context, resourceID string) (context.Context, bool, error) {
 	if h.ContextHandler == nil {
 		return ctx, true, nil
 	}
 
+	// the resources stored with WithResource during the lookup belong to this Handler.
+	ctx = context.WithValue(ctx, ctxKeyResourceOwner{}, h)
 	return h.ContextHandler.ContextWithResource(ctx, resourceID)
 }
 
+// allowedMethods returns the http methods that the Handler can reply to with the given operations.
+func allowedMethods(ops operations) []string {
+	methods := ops.Methods()
+	if _, ok := ops.Lookup(http.MethodOptions); !ok {
+		methods = append(methods, http.MethodOptions)
+		sort.Strings(methods)
+	}
+	return methods
+}
+
 type operations struct {
-	routes map[string]http.Handler
+	routes map[string]operation
+}
+
+type operation struct {
+	kind    Operation
+	handler http.Handler
 }
 
-func (o operations) Lookup(method string) (http.Handler, bool) {
+func (o operations) Lookup(method string) (operation, bool) {
 	if o.routes == nil {
-		return nil, false
+		return operation{}, false
 	}
-	h, ok := o.routes[method]
-	return h, ok
+	op, ok := o.routes[method]
+	return op, ok
 }
 
-func (o *operations) Set(httpMethod string, handler http.Handler) {
+func (o operations) IsEmpty() bool {
+	return len(o.routes) == 0
+}
+
+// Methods return the http methods registered in the operations in a sorted order.
+func (o operations) Methods() []string {
+	methods := make([]string, 0, len(o.routes))
+	for method := range o.routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func (o *operations) Set(httpMethod string, kind Operation, handler http.Handler) {
 	if o.routes == nil {
-		o.routes = make(map[string]http.Handler)
+		o.routes = make(map[string]operation)
 	}
-	o.routes[httpMethod] = handler
+	o.routes[httpMethod] = operation{kind: kind, handler: handler}
 }
 
 type handlers struct {
 	*http.ServeMux
 	prefixes       map[string]struct{}
 	hasRootHandler bool
+	registered     []registeredHandler
+}
+
+type registeredHandler struct {
+	pattern string
+	handler http.Handler
 }
 
 func (h handlers) hasHandlerWithPrefixThatMatch(path string) bool {
 	if h.prefixes == nil {
 		return false
 	}
-	_, ok := h.prefixes[h.prefix(path)]
+	if _, ok := h.prefixes[h.prefix(path)]; ok {
+		return true
+	}
+	_, ok := h.customMethodPrefix(path)
 	return ok
 }
 
+// customMethodPrefix returns the prefix of a path like /users:search,
+// when a handler is registered for the prefix, but not for the path itself.
+func (h handlers) customMethodPrefix(path string) (string, bool) {
+	prefix := h.prefix(path)
+	if _, ok := h.prefixes[prefix]; ok || strings.Trim(path, `/`) != prefix {
+		return ``, false
+	}
+	name, _, ok := splitCustomMethod(prefix)
+	if !ok {
+		return ``, false
+	}
+	_, ok = h.prefixes[name]
+	return name, ok
+}
+
+// ServeHTTP dispatches the request to the registered handlers.
+// A collection custom method like /users:search is dispatched to the handler registered for its collection,
+// which receives the request with the original path.
+func (h handlers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler(r).ServeHTTP(w, r)
+}
+
+// handler returns the registered handler that serves the request.
+func (h handlers) handler(r *http.Request) http.Handler {
+	if prefix, ok := h.customMethodPrefix(r.URL.Path); ok {
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path, r2.URL.RawPath = `/`+prefix, ``
+		if handler, pattern := h.ServeMux.Handler(r2); pattern != `` {
+			return handler
+		}
+	}
+	handler, _ := h.ServeMux.Handler(r)
+	return handler
+}
+
+// isRegistered tells if a handler is registered with the exact pattern.
+func (h handlers) isRegistered(pattern string) bool {
+	for _, rh := range h.registered {
+		if rh.pattern == pattern {
+			return true
+		}
+	}
+	return false
+}
+
 func (h handlers) prefix(path string) string {
 	for _, part := range strings.Split(path, `/`) {
 		if part != `` {
@@ -201,4 +568,5 @@ func (h *handlers) Handle(pattern string, handler http.Handler) {
 		h.ServeMux = http.NewServeMux()
 	}
 	h.ServeMux.Handle(pattern, handler)
+	h.registered = append(h.registered, registeredHandler{pattern: pattern, handler: handler})
 }
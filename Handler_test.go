@@ -90,6 +90,17 @@ func TestNewHandler(t *testing.T) {
 		})
 	})
 
+	s.Describe(`#MethodNotAllowed`, func(s *testcase.Spec) {
+		s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodPost })
+		s.Let(`path`, func(t *testcase.T) interface{} { return `/42` })
+
+		s.Then(`it will use the method not allowed method to reply`, func(t *testcase.T) {
+			resp := request(t)
+			require.Contains(t, resp.Body.String(), `method-not-allowed`)
+			require.NotEmpty(t, resp.Header().Get(`Allow`))
+		})
+	})
+
 	s.Describe(`#InternalServerError`, func(s *testcase.Spec) {
 		s.Let(`controller`, func(t *testcase.T) interface{} {
 			return struct {
@@ -110,6 +121,31 @@ func TestNewHandler(t *testing.T) {
 			require.Contains(t, request(t).Body.String(), `custom-internal-server-error`)
 		})
 	})
+
+	s.Describe(`#HandleError`, func(s *testcase.Spec) {
+		s.Let(`controller`, func(t *testcase.T) interface{} {
+			return struct {
+				InternalServerErrorController
+				ErrorHandlerController
+				ErrorContextHandler
+			}{
+				InternalServerErrorController: InternalServerErrorController{
+					Code: http.StatusInternalServerError,
+					Msg:  "custom-internal-server-error",
+				},
+				ErrorHandlerController: ErrorHandlerController{Code: http.StatusBadGateway},
+				ErrorContextHandler:    ErrorContextHandler{Err: errors.New(`boom`)},
+			}
+		})
+		s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodGet })
+		s.Let(`path`, func(t *testcase.T) interface{} { return `/42` })
+
+		s.Then(`it will use the error handler method to reply`, func(t *testcase.T) {
+			resp := request(t)
+			require.Equal(t, http.StatusBadGateway, resp.Code)
+			require.Contains(t, resp.Body.String(), `boom`)
+		})
+	})
 }
 
 func TestHandler_ServeHTTP(t *testing.T) {
@@ -347,6 +383,92 @@ func TestHandler_ServeHTTP(t *testing.T) {
 		})
 	})
 
+	s.Describe(`PUT /{resource-id} - replace`, func(s *testcase.Spec) {
+		s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodPut })
+		s.Let(`path`, func(t *testcase.T) interface{} { return fmt.Sprintf(`/%s`, resourceID(t)) })
+
+		s.When(`controller with replace action is provided`, func(s *testcase.Spec) {
+			const code = 205
+			s.Let(`controller`, func(t *testcase.T) interface{} {
+				return gorest.AsReplaceController(NewTestControllerMockHandler(t, code, `replace`))
+			})
+
+			s.Then(`it will use the replace handler`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, code, resp.Code)
+				require.Equal(t, `replace`, strings.TrimSpace(resp.Body.String()))
+			})
+		})
+
+		s.When(`controller with both replace and update action is provided`, func(s *testcase.Spec) {
+			s.Let(`controller`, func(t *testcase.T) interface{} {
+				return struct {
+					gorest.ReplaceController
+					gorest.UpdateController
+				}{
+					ReplaceController: gorest.AsReplaceController(NewTestControllerMockHandler(t, http.StatusOK, `replace`)),
+					UpdateController:  gorest.AsUpdateController(NewTestControllerMockHandler(t, http.StatusOK, `update`)),
+				}
+			})
+
+			s.Then(`replace takes precedence`, func(t *testcase.T) {
+				require.Equal(t, `replace`, strings.TrimSpace(serve(t).Body.String()))
+			})
+
+			s.And(`the request is a PATCH`, func(s *testcase.Spec) {
+				s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodPatch })
+
+				s.Then(`update is used as fallback`, func(t *testcase.T) {
+					require.Equal(t, `update`, strings.TrimSpace(serve(t).Body.String()))
+				})
+			})
+		})
+	})
+
+	s.Describe(`PATCH /{resource-id} - patch`, func(s *testcase.Spec) {
+		s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodPatch })
+		s.Let(`path`, func(t *testcase.T) interface{} { return fmt.Sprintf(`/%s`, resourceID(t)) })
+
+		s.When(`controller with patch action is provided`, func(s *testcase.Spec) {
+			const code = 206
+			s.Let(`controller`, func(t *testcase.T) interface{} {
+				return gorest.AsPatchController(NewTestControllerMockHandler(t, code, `patch`))
+			})
+
+			s.Then(`it will use the patch handler`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, code, resp.Code)
+				require.Equal(t, `patch`, strings.TrimSpace(resp.Body.String()))
+			})
+
+			s.And(`the request is a PUT`, func(s *testcase.Spec) {
+				s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodPut })
+
+				s.Then(`it will reply with method not allowed`, func(t *testcase.T) {
+					resp := serve(t)
+					require.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+					require.Equal(t, `OPTIONS, PATCH`, resp.Header().Get(`Allow`))
+				})
+			})
+		})
+
+		s.When(`controller with both patch and update action is provided`, func(s *testcase.Spec) {
+			s.Let(`controller`, func(t *testcase.T) interface{} {
+				return struct {
+					gorest.PatchController
+					gorest.UpdateController
+				}{
+					PatchController:  gorest.AsPatchController(NewTestControllerMockHandler(t, http.StatusOK, `patch`)),
+					UpdateController: gorest.AsUpdateController(NewTestControllerMockHandler(t, http.StatusOK, `update`)),
+				}
+			})
+
+			s.Then(`patch takes precedence`, func(t *testcase.T) {
+				require.Equal(t, `patch`, strings.TrimSpace(serve(t).Body.String()))
+			})
+		})
+	})
+
 	s.Describe(`DELETE /{resource-id} - delete`, func(s *testcase.Spec) {
 		s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodDelete })
 		s.Let(`path`, func(t *testcase.T) interface{} { return fmt.Sprintf(`/%s`, resourceID(t)) })
@@ -763,6 +885,166 @@ func TestHandler_ServeHTTP(t *testing.T) {
 		})
 	})
 
+	s.Describe(`HEAD`, func(s *testcase.Spec) {
+		s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodHead })
+		s.Let(`controller`, func(t *testcase.T) interface{} {
+			return StubController{
+				ListFunc: func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set(`X-List`, `true`)
+					_, _ = fmt.Fprint(w, `list`)
+				},
+				ShowFunc: func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set(`X-Show`, `true`)
+					w.WriteHeader(http.StatusAccepted)
+					_, _ = fmt.Fprint(w, `show`)
+				},
+			}
+		})
+
+		s.When(`the collection path is requested`, func(s *testcase.Spec) {
+			s.Let(`path`, func(t *testcase.T) interface{} { return `/` })
+
+			s.Then(`the list operation headers are returned without the body`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, http.StatusOK, resp.Code)
+				require.Equal(t, `true`, resp.Header().Get(`X-List`))
+				require.Equal(t, `4`, resp.Header().Get(`Content-Length`))
+				require.Empty(t, resp.Body.String())
+			})
+
+			s.And(`the controller implements list head`, func(s *testcase.Spec) {
+				s.Let(`controller`, func(t *testcase.T) interface{} {
+					return struct {
+						StubController
+						gorest.ListHeadController
+					}{
+						StubController: StubController{ListFunc: func(w http.ResponseWriter, r *http.Request) {
+							t.Fatal(`List should not be called for HEAD`)
+						}},
+						ListHeadController: ListHeadControllerFunc(func(w http.ResponseWriter, r *http.Request) {
+							w.Header().Set(`X-List-Head`, `true`)
+						}),
+					}
+				})
+
+				s.Then(`the list head action is used`, func(t *testcase.T) {
+					resp := serve(t)
+					require.Equal(t, http.StatusOK, resp.Code)
+					require.Equal(t, `true`, resp.Header().Get(`X-List-Head`))
+				})
+			})
+		})
+
+		s.When(`the resource path is requested`, func(s *testcase.Spec) {
+			s.Let(`path`, func(t *testcase.T) interface{} { return fmt.Sprintf(`/%s`, resourceID(t)) })
+
+			s.Then(`the show operation headers are returned without the body`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, http.StatusAccepted, resp.Code)
+				require.Equal(t, `true`, resp.Header().Get(`X-Show`))
+				require.Equal(t, `4`, resp.Header().Get(`Content-Length`))
+				require.Empty(t, resp.Body.String())
+			})
+
+			s.And(`the controller implements head`, func(s *testcase.Spec) {
+				s.Let(`controller`, func(t *testcase.T) interface{} {
+					return struct {
+						StubController
+						gorest.HeadController
+					}{
+						HeadController: HeadControllerFunc(func(w http.ResponseWriter, r *http.Request) {
+							w.Header().Set(`X-Head`, `true`)
+						}),
+					}
+				})
+
+				s.Then(`the head action is used`, func(t *testcase.T) {
+					resp := serve(t)
+					require.Equal(t, http.StatusOK, resp.Code)
+					require.Equal(t, `true`, resp.Header().Get(`X-Head`))
+				})
+			})
+		})
+	})
+
+	s.Describe(`method not allowed`, func(s *testcase.Spec) {
+		s.Let(`controller`, func(t *testcase.T) interface{} {
+			return StubController{}
+		})
+
+		s.When(`the collection path is requested with an unsupported method`, func(s *testcase.Spec) {
+			s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodDelete })
+			s.Let(`path`, func(t *testcase.T) interface{} { return `/` })
+
+			s.Then(`it will return with 405 and the list of the allowed methods`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+				require.Equal(t, `GET, HEAD, OPTIONS, POST`, resp.Header().Get(`Allow`))
+			})
+		})
+
+		s.When(`the resource path is requested with an unsupported method`, func(s *testcase.Spec) {
+			s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodPost })
+			s.Let(`path`, func(t *testcase.T) interface{} { return fmt.Sprintf(`/%s`, resourceID(t)) })
+
+			s.Then(`it will return with 405 and the list of the allowed methods`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+				require.Equal(t, `DELETE, GET, HEAD, OPTIONS, PATCH, PUT`, resp.Header().Get(`Allow`))
+			})
+
+			s.And(`the resource is not found`, func(s *testcase.Spec) {
+				s.Let(`controller`, func(t *testcase.T) interface{} {
+					return StubController{ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+						return ctx, false, nil
+					}}
+				})
+
+				s.Then(`it will return with 404`, func(t *testcase.T) {
+					require.Equal(t, http.StatusNotFound, serve(t).Code)
+				})
+			})
+		})
+
+		s.When(`the collection path is requested with OPTIONS`, func(s *testcase.Spec) {
+			s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodOptions })
+			s.Let(`path`, func(t *testcase.T) interface{} { return `/` })
+
+			s.Then(`it will reply with the allowed methods`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, http.StatusNoContent, resp.Code)
+				require.Equal(t, `GET, HEAD, OPTIONS, POST`, resp.Header().Get(`Allow`))
+			})
+		})
+
+		s.When(`the resource path is requested with OPTIONS`, func(s *testcase.Spec) {
+			s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodOptions })
+			s.Let(`path`, func(t *testcase.T) interface{} { return fmt.Sprintf(`/%s`, resourceID(t)) })
+
+			s.Then(`it will reply with the allowed methods`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, http.StatusNoContent, resp.Code)
+				require.Equal(t, `DELETE, GET, HEAD, OPTIONS, PATCH, PUT`, resp.Header().Get(`Allow`))
+			})
+		})
+
+		s.When(`custom method not allowed handler provided`, func(s *testcase.Spec) {
+			s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodDelete })
+			s.Let(`path`, func(t *testcase.T) interface{} { return `/` })
+
+			s.Before(func(t *testcase.T) {
+				handler(t).MethodNotAllowed = NewTestControllerMockHandler(t, http.StatusTeapot, `teapot`)
+			})
+
+			s.Then(`the custom handler will be used`, func(t *testcase.T) {
+				resp := serve(t)
+				require.Equal(t, http.StatusTeapot, resp.Code)
+				require.Equal(t, `teapot`, strings.TrimSpace(resp.Body.String()))
+				require.Equal(t, `GET, HEAD, OPTIONS, POST`, resp.Header().Get(`Allow`))
+			})
+		})
+	})
+
 	s.Describe(`#InternalServerError`, func(s *testcase.Spec) {
 		const respBody = "a custom internal server error response"
 		s.Before(func(t *testcase.T) {
@@ -783,6 +1065,37 @@ func TestHandler_ServeHTTP(t *testing.T) {
 			s.Then(`custom internal server error handler will be used`, func(t *testcase.T) {
 				require.Contains(t, serve(t).Body.String(), respBody)
 			})
+
+			s.Then(`the error is available from the request context`, func(t *testcase.T) {
+				var got error
+				handler(t).InternalServerError = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got = gorest.ErrorFromContext(r.Context())
+					w.WriteHeader(http.StatusInternalServerError)
+				})
+				serve(t)
+				require.EqualError(t, got, `boom`)
+			})
+
+			s.And(`error handler is set`, func(s *testcase.Spec) {
+				s.Let(`received error`, func(t *testcase.T) interface{} { return new(error) })
+				s.Before(func(t *testcase.T) {
+					handler(t).ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+						*t.I(`received error`).(*error) = err
+						w.WriteHeader(http.StatusServiceUnavailable)
+					}
+				})
+
+				s.Then(`it takes precedence over the internal server error handler`, func(t *testcase.T) {
+					resp := serve(t)
+					require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+					require.NotContains(t, resp.Body.String(), respBody)
+				})
+
+				s.Then(`it receives the error`, func(t *testcase.T) {
+					serve(t)
+					require.EqualError(t, *t.I(`received error`).(*error), `boom`)
+				})
+			})
 		})
 
 		s.When(`panic occurs during controller action`, func(s *testcase.Spec) {
@@ -796,6 +1109,32 @@ func TestHandler_ServeHTTP(t *testing.T) {
 				require.Contains(t, serve(t).Body.String(), respBody)
 			})
 
+			s.Then(`the recovered value is available from the request context as a PanicError`, func(t *testcase.T) {
+				var got error
+				handler(t).InternalServerError = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got = gorest.ErrorFromContext(r.Context())
+					w.WriteHeader(http.StatusInternalServerError)
+				})
+				serve(t)
+				var perr *gorest.PanicError
+				require.True(t, errors.As(got, &perr))
+				require.Equal(t, `boom`, perr.Value)
+			})
+
+			s.And(`error handler also panics`, func(s *testcase.Spec) {
+				s.Before(func(t *testcase.T) {
+					handler(t).ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+						panic(`boom`)
+					}
+				})
+
+				s.Then(`generic internal server error is used as fallback`, func(t *testcase.T) {
+					resp := serve(t)
+					require.Equal(t, http.StatusInternalServerError, resp.Code)
+					require.Contains(t, resp.Body.String(), http.StatusText(http.StatusInternalServerError))
+				})
+			})
+
 			s.And(`internal server error also return with panic`, func(s *testcase.Spec) {
 				s.Before(func(t *testcase.T) {
 					handler(t).InternalServerError = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -811,6 +1150,90 @@ func TestHandler_ServeHTTP(t *testing.T) {
 			})
 		})
 	})
+
+	s.Describe(`#PanicHandler`, func(s *testcase.Spec) {
+		type panicReport struct {
+			r     *http.Request
+			cause interface{}
+			stack []byte
+		}
+		s.Let(`reports`, func(t *testcase.T) interface{} { return &[]panicReport{} })
+		var reports = func(t *testcase.T) []panicReport { return *t.I(`reports`).(*[]panicReport) }
+		s.Before(func(t *testcase.T) {
+			handler(t).PanicHandler = func(r *http.Request, cause interface{}, stack []byte) {
+				ptr := t.I(`reports`).(*[]panicReport)
+				*ptr = append(*ptr, panicReport{r: r, cause: cause, stack: stack})
+			}
+		})
+
+		s.Let(`method`, func(t *testcase.T) interface{} { return http.MethodGet })
+		s.Let(`path`, func(t *testcase.T) interface{} { return `/42` })
+		s.Let(`show`, func(t *testcase.T) interface{} {
+			return func(w http.ResponseWriter, r *http.Request) { panic(`boom`) }
+		})
+		s.Let(`controller`, func(t *testcase.T) interface{} {
+			return gorest.AsShowController(http.HandlerFunc(t.I(`show`).(func(http.ResponseWriter, *http.Request))))
+		})
+
+		s.Then(`the panic is reported with its stack trace`, func(t *testcase.T) {
+			resp := serve(t)
+			require.Equal(t, http.StatusInternalServerError, resp.Code)
+			require.Len(t, reports(t), 1)
+			require.Equal(t, `boom`, reports(t)[0].cause)
+			require.Equal(t, `/42`, reports(t)[0].r.RequestURI)
+			require.Contains(t, string(reports(t)[0].stack), `Handler_test.go`)
+		})
+
+		s.Then(`the stack trace is part of the PanicError`, func(t *testcase.T) {
+			var got error
+			handler(t).ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) { got = err }
+			serve(t)
+			var perr *gorest.PanicError
+			require.True(t, errors.As(got, &perr))
+			require.Equal(t, reports(t)[0].stack, perr.Stack)
+		})
+
+		s.And(`the controller is mounted under a Handler that has the PanicHandler`, func(s *testcase.Spec) {
+			s.Let(`path`, func(t *testcase.T) interface{} { return `/42/pets/7` })
+			s.Before(func(t *testcase.T) {
+				gorest.Mount(handler(t), `/pets/`, gorest.NewHandler(t.I(`controller`)))
+			})
+
+			s.Then(`the panic is reported to the outer PanicHandler`, func(t *testcase.T) {
+				require.Equal(t, http.StatusInternalServerError, serve(t).Code)
+				require.Len(t, reports(t), 1)
+			})
+		})
+
+		s.And(`the controller aborts the request with http.ErrAbortHandler`, func(s *testcase.Spec) {
+			s.Let(`show`, func(t *testcase.T) interface{} {
+				return func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) }
+			})
+
+			s.Then(`the panic is propagated to net/http`, func(t *testcase.T) {
+				require.PanicsWithValue(t, http.ErrAbortHandler, func() { serve(t) })
+				require.Empty(t, reports(t))
+			})
+		})
+
+		s.And(`the response was already started`, func(s *testcase.Spec) {
+			s.Let(`show`, func(t *testcase.T) interface{} {
+				return func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte(`partial body`))
+					panic(`boom`)
+				}
+			})
+
+			s.Then(`the response is aborted after the panic is reported`, func(t *testcase.T) {
+				w := httptest.NewRecorder()
+				require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+					handler(t).ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/42`, nil))
+				})
+				require.Equal(t, `partial body`, w.Body.String())
+				require.Len(t, reports(t), 1)
+			})
+		})
+	})
 }
 
 func BenchmarkController_ServeHTTP(b *testing.B) {
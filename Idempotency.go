@@ -0,0 +1,276 @@
+package gorest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrIdempotencyKeyReused represents a request that reuses an idempotency key of a different request.
+	ErrIdempotencyKeyReused = errors.New(`gorest: idempotency key is used by a different request`)
+	// ErrIdempotencyKeyInFlight represents a retry of an idempotent request that is still in progress.
+	ErrIdempotencyKeyInFlight = errors.New(`gorest: request with the idempotency key is in progress`)
+)
+
+// IdempotentResponse is a response recorded for an idempotency key.
+type IdempotentResponse struct {
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyRecord is the state of an idempotency key in an IdempotencyStore.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request that reserved the key.
+	Fingerprint string
+	// Response is the recorded response of the request.
+	// It is nil while the request is in progress.
+	Response *IdempotentResponse
+}
+
+// IdempotencyStore stores the responses of the requests made with an Idempotency-Key header.
+// The implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Reserve claims the key for the request with the fingerprint, and reports with reserved whether it succeeded.
+	// When the key is already claimed, the existing record is returned.
+	Reserve(ctx context.Context, key, fingerprint string) (record IdempotencyRecord, reserved bool, err error)
+	// Save records the response of the request that reserved the key.
+	Save(ctx context.Context, key string, response IdempotentResponse) error
+	// Release frees the key of a request that has no response to replay, so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotent is a Middleware that honours the Idempotency-Key request header with the store.
+// The first response for a key is recorded, and replayed for the retries of the same request
+// with an Idempotent-Replayed header.
+//
+// A retry with a different method, path or body is rejected with ErrIdempotencyKeyReused,
+// and a retry while the first request is still in progress is rejected with ErrIdempotencyKeyInFlight.
+// Server error responses are not recorded, so those requests can be retried.
+// Requests without an Idempotency-Key header are served as usual.
+//
+// The scope tells whose keys the request uses, like the id of the authenticated principal or tenant,
+// and the keys of different scopes never meet in the store.
+// When scope is nil, every requester shares the same keys,
+// so a requester who sends the key of another requester with the same request gets the response of the other requester.
+//
+// The Idempotency field of the Handler applies it to the Create operation with the IdempotencyScope of the Handler,
+// and it can be used for custom operations with UseFor:
+//
+//	h.UseFor(gorest.OpCustom, gorest.Idempotent(store, principalID))
+func Idempotent(store IdempotencyStore, scope func(r *http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(`Idempotency-Key`)
+			if key == `` {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if scope != nil {
+				key = scopedIdempotencyKey(scope(r), key)
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				WriteError(w, r, fmt.Errorf(`%w: %v`, ErrBadRequest, err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := requestFingerprint(r, body)
+			record, reserved, err := store.Reserve(r.Context(), key, fingerprint)
+			if err != nil {
+				WriteError(w, r, err)
+				return
+			}
+			if !reserved {
+				switch {
+				case record.Fingerprint != fingerprint:
+					WriteError(w, r, ErrIdempotencyKeyReused)
+				case record.Response == nil:
+					WriteError(w, r, ErrIdempotencyKeyInFlight)
+				default:
+					replayIdempotentResponse(w, *record.Response)
+				}
+				return
+			}
+
+			rw := &idempotentResponseWriter{ResponseWriter: w, inherited: w.Header().Clone()}
+			saved := false
+			defer func() {
+				if !saved {
+					_ = store.Release(r.Context(), key)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			response := rw.response()
+			if response.Code < http.StatusInternalServerError {
+				saved = store.Save(r.Context(), key, response) == nil
+			}
+		})
+	}
+}
+
+// scopedIdempotencyKey prefixes the key with the length of the scope and the scope,
+// so the keys of different scopes can't collide.
+func scopedIdempotencyKey(scope, key string) string {
+	return strconv.Itoa(len(scope)) + `:` + scope + `:` + key
+}
+
+// requestFingerprint identifies the request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s %s\n", r.Method, requestURI(r))
+	_, _ = hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, response IdempotentResponse) {
+	for name, values := range response.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set(`Idempotent-Replayed`, `true`)
+	w.WriteHeader(response.Code)
+	_, _ = w.Write(response.Body)
+}
+
+// idempotentResponseWriter records the response while it is written to the requester.
+// Only the headers the wrapped handler sets are recorded,
+// as the inherited headers, like the CORS headers of the origin, belong to the request and not to the operation.
+type idempotentResponseWriter struct {
+	http.ResponseWriter
+	inherited http.Header
+	code      int
+	header    http.Header
+	body      bytes.Buffer
+}
+
+func (w *idempotentResponseWriter) WriteHeader(code int) {
+	if w.code == 0 && http.StatusOK <= code {
+		w.code = code
+		w.header = make(http.Header)
+		for name, values := range w.Header() {
+			if !equalHeaderValues(w.inherited[name], values) {
+				w.header[name] = append([]string(nil), values...)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func equalHeaderValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *idempotentResponseWriter) Write(bs []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(bs)
+	return w.ResponseWriter.Write(bs)
+}
+
+func (w *idempotentResponseWriter) response() IdempotentResponse {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return IdempotentResponse{Code: w.code, Header: w.header, Body: w.body.Bytes()}
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore, meant for tests and single instance services.
+// The keys expire after the TTL, which defaults to 24 hours.
+// An expired key can be reserved again right away, while the expired keys are swept from the memory once per TTL.
+// The zero value is ready to use.
+type MemoryIdempotencyStore struct {
+	TTL time.Duration
+
+	mutex     sync.Mutex
+	records   map[string]memoryIdempotencyRecord
+	nextSweep time.Time
+}
+
+type memoryIdempotencyRecord struct {
+	IdempotencyRecord
+	expiresAt time.Time
+}
+
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string) (IdempotencyRecord, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	if record, ok := s.records[key]; ok && now.Before(record.expiresAt) {
+		return record.IdempotencyRecord, false, nil
+	}
+
+	if s.records == nil {
+		s.records = make(map[string]memoryIdempotencyRecord)
+	}
+	record := IdempotencyRecord{Fingerprint: fingerprint}
+	s.records[key] = memoryIdempotencyRecord{IdempotencyRecord: record, expiresAt: now.Add(s.ttl())}
+	return record, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Save(ctx context.Context, key string, response IdempotentResponse) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return fmt.Errorf(`gorest: idempotency key %q is not reserved`, key)
+	}
+	response.Header = response.Header.Clone()
+	response.Body = append([]byte(nil), response.Body...)
+	record.Response = &response
+	record.expiresAt = time.Now().Add(s.ttl())
+	s.records[key] = record
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// sweep evicts the expired keys, when the last sweep was at least a TTL ago.
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(s.ttl())
+	for key, record := range s.records {
+		if !now.Before(record.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
+
+func (s *MemoryIdempotencyStore) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return 24 * time.Hour
+	}
+	return s.TTL
+}
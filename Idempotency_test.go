@@ -0,0 +1,220 @@
+package gorest_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestHandler_Idempotency(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`store`, func(t *testcase.T) interface{} { return &gorest.MemoryIdempotencyStore{} })
+	s.Let(`created`, func(t *testcase.T) interface{} { return new(int) })
+	var created = func(t *testcase.T) int { return *t.I(`created`).(*int) }
+	s.Let(`code`, func(t *testcase.T) interface{} { return http.StatusCreated })
+	s.Let(`hook`, func(t *testcase.T) interface{} { return func() {} })
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		h := gorest.NewHandler(StubController{CreateFunc: func(w http.ResponseWriter, r *http.Request) {
+			t.I(`hook`).(func())()
+			ptr := t.I(`created`).(*int)
+			*ptr++
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set(`Location`, `/`+strconv.Itoa(*ptr))
+			w.WriteHeader(t.I(`code`).(int))
+			_, _ = w.Write(body)
+		}})
+		h.Idempotency = t.I(`store`).(gorest.IdempotencyStore)
+		return h
+	})
+	var serve = func(t *testcase.T, method, path, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != `` {
+			r.Header.Set(`Idempotency-Key`, key)
+		}
+		w := httptest.NewRecorder()
+		t.I(`handler`).(*gorest.Handler).ServeHTTP(w, r)
+		return w
+	}
+
+	s.Test(`retries are replayed from the first response`, func(t *testcase.T) {
+		first := serve(t, http.MethodPost, `/`, `k1`, `payment`)
+		require.Equal(t, http.StatusCreated, first.Code)
+		require.Empty(t, first.Header().Get(`Idempotent-Replayed`))
+
+		retry := serve(t, http.MethodPost, `/`, `k1`, `payment`)
+		require.Equal(t, http.StatusCreated, retry.Code)
+		require.Equal(t, `/1`, retry.Header().Get(`Location`))
+		require.Equal(t, `payment`, retry.Body.String())
+		require.Equal(t, `true`, retry.Header().Get(`Idempotent-Replayed`))
+		require.Equal(t, 1, created(t))
+	})
+
+	s.Test(`different keys are different requests`, func(t *testcase.T) {
+		serve(t, http.MethodPost, `/`, `k1`, `payment`)
+		serve(t, http.MethodPost, `/`, `k2`, `payment`)
+		require.Equal(t, 2, created(t))
+	})
+
+	s.Test(`requests without key are not recorded`, func(t *testcase.T) {
+		serve(t, http.MethodPost, `/`, ``, `payment`)
+		serve(t, http.MethodPost, `/`, ``, `payment`)
+		require.Equal(t, 2, created(t))
+	})
+
+	s.Test(`reusing the key with a different body is rejected with 422`, func(t *testcase.T) {
+		serve(t, http.MethodPost, `/`, `k1`, `payment`)
+		resp := serve(t, http.MethodPost, `/`, `k1`, `other payment`)
+		require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		require.Equal(t, 1, created(t))
+	})
+
+	s.Test(`retry of an in-flight request is rejected with 409`, func(t *testcase.T) {
+		entered, release := make(chan struct{}), make(chan struct{})
+		t.Let(`hook`, func() {
+			close(entered)
+			<-release
+		})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			serve(t, http.MethodPost, `/`, `k1`, `payment`)
+		}()
+		<-entered
+
+		resp := serve(t, http.MethodPost, `/`, `k1`, `payment`)
+		close(release)
+		<-done
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Equal(t, 1, created(t))
+	})
+
+	s.When(`the requests come from different origins`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			t.I(`handler`).(*gorest.Handler).CORS = &gorest.CORS{AllowedOrigins: []string{`https://a.com`, `https://b.com`}}
+		})
+		var serveFrom = func(t *testcase.T, origin string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, `/`, strings.NewReader(`payment`))
+			r.Header.Set(`Idempotency-Key`, `k1`)
+			r.Header.Set(`Origin`, origin)
+			w := httptest.NewRecorder()
+			t.I(`handler`).(*gorest.Handler).ServeHTTP(w, r)
+			return w
+		}
+
+		s.Then(`the retry keeps the headers of its own origin`, func(t *testcase.T) {
+			serveFrom(t, `https://a.com`)
+			resp := serveFrom(t, `https://b.com`)
+			require.Equal(t, `true`, resp.Header().Get(`Idempotent-Replayed`))
+			require.Equal(t, `/1`, resp.Header().Get(`Location`))
+			require.Equal(t, `https://b.com`, resp.Header().Get(`Access-Control-Allow-Origin`))
+		})
+	})
+
+	s.When(`the keys are scoped to the requester`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			t.I(`handler`).(*gorest.Handler).IdempotencyScope = func(r *http.Request) string { return r.Header.Get(`X-User`) }
+		})
+		var serveAs = func(t *testcase.T, user string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, `/`, strings.NewReader(`payment`))
+			r.Header.Set(`Idempotency-Key`, `k1`)
+			r.Header.Set(`X-User`, user)
+			w := httptest.NewRecorder()
+			t.I(`handler`).(*gorest.Handler).ServeHTTP(w, r)
+			return w
+		}
+
+		s.Then(`the same key of another requester is a different request`, func(t *testcase.T) {
+			serveAs(t, `alice`)
+			resp := serveAs(t, `bob`)
+			require.Empty(t, resp.Header().Get(`Idempotent-Replayed`))
+			require.Equal(t, `/2`, resp.Header().Get(`Location`))
+			require.Equal(t, 2, created(t))
+		})
+
+		s.Then(`the retries of the same requester are replayed`, func(t *testcase.T) {
+			serveAs(t, `alice`)
+			resp := serveAs(t, `alice`)
+			require.Equal(t, `true`, resp.Header().Get(`Idempotent-Replayed`))
+			require.Equal(t, 1, created(t))
+		})
+	})
+
+	s.When(`the request fails with a server error`, func(s *testcase.Spec) {
+		s.Let(`code`, func(t *testcase.T) interface{} { return http.StatusServiceUnavailable })
+
+		s.Then(`it is not recorded, so it can be retried`, func(t *testcase.T) {
+			serve(t, http.MethodPost, `/`, `k1`, `payment`)
+			serve(t, http.MethodPost, `/`, `k1`, `payment`)
+			require.Equal(t, 2, created(t))
+		})
+	})
+
+	s.Test(`custom operations can be made idempotent with UseFor`, func(t *testcase.T) {
+		handler := t.I(`handler`).(*gorest.Handler)
+		var calls int
+		handler.Handle(`/refund`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+		handler.UseFor(gorest.OpCustom, gorest.Idempotent(t.I(`store`).(gorest.IdempotencyStore), nil))
+		serve(t, http.MethodPost, `/1/refund`, `k1`, ``)
+		serve(t, http.MethodPost, `/1/refund`, `k1`, ``)
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	s := testcase.NewSpec(t)
+	ctx := context.Background()
+
+	s.Test(`reserve, save and replay`, func(t *testcase.T) {
+		store := &gorest.MemoryIdempotencyStore{}
+		_, reserved, err := store.Reserve(ctx, `k`, `fp`)
+		require.Nil(t, err)
+		require.True(t, reserved)
+
+		record, reserved, _ := store.Reserve(ctx, `k`, `fp`)
+		require.False(t, reserved)
+		require.Equal(t, gorest.IdempotencyRecord{Fingerprint: `fp`}, record)
+
+		require.Nil(t, store.Save(ctx, `k`, gorest.IdempotentResponse{Code: http.StatusCreated, Body: []byte(`ok`)}))
+		record, _, _ = store.Reserve(ctx, `k`, `fp`)
+		require.Equal(t, &gorest.IdempotentResponse{Code: http.StatusCreated, Body: []byte(`ok`)}, record.Response)
+	})
+
+	s.Test(`released keys can be reserved again`, func(t *testcase.T) {
+		store := &gorest.MemoryIdempotencyStore{}
+		_, _, _ = store.Reserve(ctx, `k`, `fp`)
+		require.Nil(t, store.Release(ctx, `k`))
+		_, reserved, _ := store.Reserve(ctx, `k`, `fp`)
+		require.True(t, reserved)
+	})
+
+	s.Test(`keys are evicted after the TTL`, func(t *testcase.T) {
+		store := &gorest.MemoryIdempotencyStore{TTL: 10 * time.Millisecond}
+		_, _, _ = store.Reserve(ctx, `k`, `fp`)
+		time.Sleep(20 * time.Millisecond)
+		_, reserved, _ := store.Reserve(ctx, `k`, `fp`)
+		require.True(t, reserved)
+	})
+
+	s.Test(`expired keys are swept once per TTL`, func(t *testcase.T) {
+		store := &gorest.MemoryIdempotencyStore{TTL: 10 * time.Millisecond}
+		_, _, _ = store.Reserve(ctx, `k1`, `fp`)
+		time.Sleep(20 * time.Millisecond)
+		_, _, _ = store.Reserve(ctx, `k2`, `fp`)
+		require.Error(t, store.Save(ctx, `k1`, gorest.IdempotentResponse{}))
+	})
+
+	s.Test(`saving an unreserved key fails`, func(t *testcase.T) {
+		require.Error(t, (&gorest.MemoryIdempotencyStore{}).Save(ctx, `k`, gorest.IdempotentResponse{}))
+	})
+}
@@ -0,0 +1,478 @@
+package gorest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ListQuery is the parsed filter and sort query parameters of a List request.
+//
+// example:
+//
+//	GET /?filter=status eq "active" and created gt 2024-01-01&sort=-created,name
+type ListQuery struct {
+	// Filter is the parsed filter expression, or nil when the request has no filter.
+	Filter FilterExpr
+	// Sort is the sort order in the order of precedence.
+	Sort []SortField
+}
+
+// FilterExpr is a node of a parsed filter expression.
+// It is one of *FilterComparison, *FilterLogical and *FilterNot.
+type FilterExpr interface {
+	filterExpr()
+}
+
+// FilterOp is a comparison operator of the filter language.
+type FilterOp string
+
+const (
+	FilterEq       FilterOp = `eq`
+	FilterNe       FilterOp = `ne`
+	FilterGt       FilterOp = `gt`
+	FilterGe       FilterOp = `ge`
+	FilterLt       FilterOp = `lt`
+	FilterLe       FilterOp = `le`
+	FilterContains FilterOp = `contains`
+)
+
+var filterOps = map[FilterOp]struct{}{
+	FilterEq: {}, FilterNe: {}, FilterGt: {}, FilterGe: {}, FilterLt: {}, FilterLe: {}, FilterContains: {},
+}
+
+// FilterComparison compares a field with a value: status eq "active"
+type FilterComparison struct {
+	Field string
+	Op    FilterOp
+	Value FilterValue
+	// Offset is the byte offset of the field in the filter parameter.
+	Offset int
+	// OpOffset is the byte offset of the operator in the filter parameter.
+	OpOffset int
+}
+
+// FilterLogical combines two expressions with "and" or "or".
+type FilterLogical struct {
+	// Op is either "and" or "or".
+	Op    string
+	Left  FilterExpr
+	Right FilterExpr
+}
+
+// FilterNot negates an expression.
+type FilterNot struct {
+	Expr FilterExpr
+}
+
+func (*FilterComparison) filterExpr() {}
+func (*FilterLogical) filterExpr()    {}
+func (*FilterNot) filterExpr()        {}
+
+// FilterValueKind is the kind of a literal in the filter language.
+type FilterValueKind int
+
+const (
+	// FilterLiteral is an unquoted value that is not a number, boolean or null, like 2024-01-01.
+	FilterLiteral FilterValueKind = iota
+	// FilterString is a double quoted value.
+	FilterString
+	FilterNumber
+	FilterBool
+	FilterNull
+)
+
+// FilterValue is a value of a comparison.
+type FilterValue struct {
+	Kind FilterValueKind
+	// Raw is the value as text, without the quotes and the escapes of a string.
+	Raw string
+	// Offset is the byte offset of the value in the filter parameter.
+	Offset int
+}
+
+// SortField is an element of the sort order: -created
+type SortField struct {
+	Field string
+	Desc  bool
+	// Offset is the byte offset of the field in the sort parameter.
+	Offset int
+}
+
+// QuerySchema is the whitelist of the fields that can be used in the filter and sort query parameters.
+type QuerySchema struct {
+	Fields map[string]QueryField
+}
+
+// QueryField describes how a field can be used in the list query.
+type QueryField struct {
+	// Operators are the filter operators allowed on the field.
+	// When it is empty, the field can't be used in the filter.
+	Operators []FilterOp
+	// Sortable allows the field to be used in the sort parameter.
+	Sortable bool
+}
+
+// WithListQuerySchema is an optional controller interface to accept the filter and sort query parameters in List.
+// The Handler parses and validates the query parameters against the schema before List is called,
+// and replies with 400 when they are invalid.
+// The parsed query can be retrieved with ListQueryFromContext.
+type WithListQuerySchema interface {
+	ListQuerySchema() QuerySchema
+}
+
+// QueryError is an invalid filter or sort query parameter.
+// It matches ErrBadRequest.
+type QueryError struct {
+	// Param is the name of the query parameter, like filter or sort.
+	Param string
+	// Offset is the byte offset of the error in the query parameter.
+	Offset int
+	Msg    string
+}
+
+func (err *QueryError) Error() string {
+	return fmt.Sprintf(`gorest: invalid %s at offset %d: %s`, err.Param, err.Offset, err.Msg)
+}
+
+func (err *QueryError) Is(target error) bool { return target == ErrBadRequest }
+
+const (
+	filterParam = `filter`
+	sortParam   = `sort`
+)
+
+// ParseListQuery parses the filter and sort query parameters of the request, and validates them against the schema.
+// The returned error is a *QueryError when the query parameters are invalid.
+func ParseListQuery(r *http.Request, schema QuerySchema) (ListQuery, error) {
+	var (
+		q     ListQuery
+		err   error
+		query = r.URL.Query()
+	)
+	if filter := query.Get(filterParam); strings.TrimSpace(filter) != `` {
+		if q.Filter, err = ParseFilter(filter); err != nil {
+			return ListQuery{}, err
+		}
+		if err := schema.validateFilter(q.Filter); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	if sort := query.Get(sortParam); sort != `` {
+		if q.Sort, err = ParseSort(sort); err != nil {
+			return ListQuery{}, err
+		}
+		if err := schema.validateSort(q.Sort); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	return q, nil
+}
+
+func (schema QuerySchema) validateFilter(expr FilterExpr) error {
+	switch expr := expr.(type) {
+	case *FilterLogical:
+		if err := schema.validateFilter(expr.Left); err != nil {
+			return err
+		}
+		return schema.validateFilter(expr.Right)
+	case *FilterNot:
+		return schema.validateFilter(expr.Expr)
+	case *FilterComparison:
+		field, ok := schema.Fields[expr.Field]
+		if !ok || len(field.Operators) == 0 {
+			return &QueryError{Param: filterParam, Offset: expr.Offset, Msg: fmt.Sprintf(`field %q can't be filtered`, expr.Field)}
+		}
+		for _, op := range field.Operators {
+			if op == expr.Op {
+				return nil
+			}
+		}
+		return &QueryError{Param: filterParam, Offset: expr.OpOffset, Msg: fmt.Sprintf(`operator %q is not allowed on field %q`, expr.Op, expr.Field)}
+	default:
+		return nil
+	}
+}
+
+func (schema QuerySchema) validateSort(fields []SortField) error {
+	for _, sf := range fields {
+		if field, ok := schema.Fields[sf.Field]; !ok || !field.Sortable {
+			return &QueryError{Param: sortParam, Offset: sf.Offset, Msg: fmt.Sprintf(`field %q can't be sorted`, sf.Field)}
+		}
+	}
+	return nil
+}
+
+// ParseSort parses a comma separated sort order, where a "-" prefix means descending order: -created,name
+func ParseSort(sort string) ([]SortField, error) {
+	var (
+		fields []SortField
+		seen   = make(map[string]struct{})
+		offset int
+	)
+	for _, part := range strings.Split(sort, `,`) {
+		sf := SortField{Field: strings.TrimSpace(part), Offset: offset + strings.Index(part, strings.TrimSpace(part))}
+		offset += len(part) + 1
+
+		switch {
+		case strings.HasPrefix(sf.Field, `-`):
+			sf.Field, sf.Desc = sf.Field[1:], true
+		case strings.HasPrefix(sf.Field, `+`):
+			sf.Field = sf.Field[1:]
+		}
+		if !isFilterIdentifier(sf.Field) {
+			return nil, &QueryError{Param: sortParam, Offset: sf.Offset, Msg: `expected field name`}
+		}
+		if _, ok := seen[sf.Field]; ok {
+			return nil, &QueryError{Param: sortParam, Offset: sf.Offset, Msg: fmt.Sprintf(`field %q is repeated`, sf.Field)}
+		}
+		seen[sf.Field] = struct{}{}
+		fields = append(fields, sf)
+	}
+	return fields, nil
+}
+
+// ParseFilter parses a filter expression.
+//
+//	expr       = or
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field op value
+//	op         = "eq" | "ne" | "gt" | "ge" | "lt" | "le" | "contains"
+//	value      = "quoted string" | number | true | false | null | literal
+func ParseFilter(filter string) (FilterExpr, error) {
+	tokens, err := lexFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, `unexpected %s`, tok)
+	}
+	return expr, nil
+}
+
+type filterTokenKind int
+
+const (
+	tokenEOF filterTokenKind = iota
+	tokenWord
+	tokenString
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind   filterTokenKind
+	text   string
+	offset int
+}
+
+func lexFilter(src string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(src); {
+		switch c := src[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, text: `(`, offset: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, text: `)`, offset: i})
+			i++
+		case c == '"':
+			var sb strings.Builder
+			start := i
+			for i++; ; i++ {
+				if len(src) <= i {
+					return nil, &QueryError{Param: filterParam, Offset: start, Msg: `unterminated string`}
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					sb.WriteByte(src[i])
+					continue
+				}
+				if src[i] == '"' {
+					i++
+					break
+				}
+				sb.WriteByte(src[i])
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: sb.String(), offset: start})
+		default:
+			start := i
+			for i < len(src) && !strings.ContainsRune(" \t\n\r()\"", rune(src[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: src[start:i], offset: start})
+		}
+	}
+	return append(tokens, filterToken{kind: tokenEOF, offset: len(src)}), nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken { return p.tokens[p.pos] }
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == tokenWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...interface{}) error {
+	return &QueryError{Param: filterParam, Offset: tok.offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (tok filterToken) String() string {
+	if tok.kind == tokenEOF {
+		return `end of filter`
+	}
+	return strconv.Quote(tok.text)
+}
+
+func (p *filterParser) parseOr() (FilterExpr, error) {
+	return p.parseLogical(`or`, p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (FilterExpr, error) {
+	return p.parseLogical(`and`, p.parseUnary)
+}
+
+func (p *filterParser) parseLogical(keyword string, operand func() (FilterExpr, error)) (FilterExpr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(keyword) {
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &FilterLogical{Op: keyword, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (FilterExpr, error) {
+	if p.isKeyword(`not`) {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &FilterNot{Expr: expr}, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenRParen {
+			return nil, p.errorf(tok, `expected ")" but found %s`, tok)
+		}
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (FilterExpr, error) {
+	field := p.next()
+	if field.kind != tokenWord || !isFilterIdentifier(field.text) {
+		return nil, p.errorf(field, `expected field name but found %s`, field)
+	}
+
+	op := p.next()
+	if _, ok := filterOps[FilterOp(strings.ToLower(op.text))]; op.kind != tokenWord || !ok {
+		return nil, p.errorf(op, `expected operator but found %s`, op)
+	}
+
+	value := p.next()
+	fv := FilterValue{Raw: value.text, Offset: value.offset}
+	switch {
+	case value.kind == tokenString:
+		fv.Kind = FilterString
+	case value.kind != tokenWord:
+		return nil, p.errorf(value, `expected value but found %s`, value)
+	case value.text == `true` || value.text == `false`:
+		fv.Kind = FilterBool
+	case value.text == `null`:
+		fv.Kind = FilterNull
+	case isFilterNumber(value.text):
+		fv.Kind = FilterNumber
+	default:
+		fv.Kind = FilterLiteral
+	}
+
+	return &FilterComparison{
+		Field:    field.text,
+		Op:       FilterOp(strings.ToLower(op.text)),
+		Value:    fv,
+		Offset:   field.offset,
+		OpOffset: op.offset,
+	}, nil
+}
+
+func isFilterIdentifier(s string) bool {
+	if s == `` {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case 0 < i && (c == '.' || '0' <= c && c <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isFilterNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+type ctxKeyListQuery struct{}
+
+// ListQueryFromContext returns the list query that the Handler parsed for a controller with WithListQuerySchema.
+func ListQueryFromContext(ctx context.Context) (ListQuery, bool) {
+	q, ok := ctx.Value(ctxKeyListQuery{}).(ListQuery)
+	return q, ok
+}
+
+// listQueryHandler parses the list query before the List operation.
+type listQueryHandler struct {
+	http.Handler
+	schema QuerySchema
+}
+
+func (h listQueryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q, err := ParseListQuery(r, h.schema)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	h.Handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyListQuery{}, q)))
+}
@@ -0,0 +1,276 @@
+package gorest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestParseFilter(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`comparisons combined with precedence`, func(t *testcase.T) {
+		expr, err := gorest.ParseFilter(`status eq "active" or not (age lt 18) and created gt 2024-01-01`)
+		require.Nil(t, err)
+		require.Equal(t, &gorest.FilterLogical{
+			Op: `or`,
+			Left: &gorest.FilterComparison{
+				Field: `status`, Op: gorest.FilterEq, Offset: 0, OpOffset: 7,
+				Value: gorest.FilterValue{Kind: gorest.FilterString, Raw: `active`, Offset: 10},
+			},
+			Right: &gorest.FilterLogical{
+				Op: `and`,
+				Left: &gorest.FilterNot{Expr: &gorest.FilterComparison{
+					Field: `age`, Op: gorest.FilterLt, Offset: 27, OpOffset: 31,
+					Value: gorest.FilterValue{Kind: gorest.FilterNumber, Raw: `18`, Offset: 34},
+				}},
+				Right: &gorest.FilterComparison{
+					Field: `created`, Op: gorest.FilterGt, Offset: 42, OpOffset: 50,
+					Value: gorest.FilterValue{Kind: gorest.FilterLiteral, Raw: `2024-01-01`, Offset: 53},
+				},
+			},
+		}, expr)
+	})
+
+	s.Test(`value kinds`, func(t *testcase.T) {
+		for filter, kind := range map[string]gorest.FilterValueKind{
+			`a eq "x \"y\""`: gorest.FilterString,
+			`a eq 4.2`:       gorest.FilterNumber,
+			`a eq true`:      gorest.FilterBool,
+			`a eq null`:      gorest.FilterNull,
+			`a eq x`:         gorest.FilterLiteral,
+		} {
+			expr, err := gorest.ParseFilter(filter)
+			require.Nil(t, err)
+			require.Equal(t, kind, expr.(*gorest.FilterComparison).Value.Kind, filter)
+		}
+	})
+
+	for filter, expected := range map[string]gorest.QueryError{
+		`status eq`:              {Param: `filter`, Offset: 9, Msg: `expected value but found end of filter`},
+		`status is "active"`:     {Param: `filter`, Offset: 7, Msg: `expected operator but found "is"`},
+		`(status eq "active"`:    {Param: `filter`, Offset: 19, Msg: `expected ")" but found end of filter`},
+		`status eq "active`:      {Param: `filter`, Offset: 10, Msg: `unterminated string`},
+		`status eq "active" age`: {Param: `filter`, Offset: 19, Msg: `unexpected "age"`},
+		`1st eq 1`:               {Param: `filter`, Offset: 0, Msg: `expected field name but found "1st"`},
+	} {
+		filter, expected := filter, expected
+		s.Test(`error: `+filter, func(t *testcase.T) {
+			_, err := gorest.ParseFilter(filter)
+			var qerr *gorest.QueryError
+			require.True(t, errors.As(err, &qerr), err)
+			require.Equal(t, expected, *qerr)
+			require.True(t, errors.Is(err, gorest.ErrBadRequest))
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`fields in order`, func(t *testcase.T) {
+		fields, err := gorest.ParseSort(`-created, name`)
+		require.Nil(t, err)
+		require.Equal(t, []gorest.SortField{
+			{Field: `created`, Desc: true, Offset: 0},
+			{Field: `name`, Offset: 10},
+		}, fields)
+	})
+
+	s.Test(`empty field`, func(t *testcase.T) {
+		_, err := gorest.ParseSort(`name,,age`)
+		require.Equal(t, &gorest.QueryError{Param: `sort`, Offset: 5, Msg: `expected field name`}, err)
+	})
+
+	s.Test(`repeated field`, func(t *testcase.T) {
+		_, err := gorest.ParseSort(`name,-name`)
+		require.Equal(t, &gorest.QueryError{Param: `sort`, Offset: 5, Msg: `field "name" is repeated`}, err)
+	})
+}
+
+type Order struct {
+	ID      int       `json:"id"`
+	Status  string    `json:"status"`
+	Total   float64   `json:"total"`
+	Created time.Time `json:"created"`
+	Note    *string   `json:"note"`
+}
+
+var orderQuerySchema = gorest.QuerySchema{Fields: map[string]gorest.QueryField{
+	`status`:  {Operators: []gorest.FilterOp{gorest.FilterEq, gorest.FilterNe}, Sortable: true},
+	`total`:   {Operators: []gorest.FilterOp{gorest.FilterGt, gorest.FilterLt}, Sortable: true},
+	`created`: {Operators: []gorest.FilterOp{gorest.FilterGt, gorest.FilterLt}, Sortable: true},
+	`note`:    {Operators: []gorest.FilterOp{gorest.FilterEq, gorest.FilterContains}},
+}}
+
+func TestParseListQuery(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	var parse = func(filter, sort string) (gorest.ListQuery, error) {
+		query := url.Values{}
+		query.Set(`filter`, filter)
+		query.Set(`sort`, sort)
+		return gorest.ParseListQuery(httptest.NewRequest(http.MethodGet, `/?`+query.Encode(), nil), orderQuerySchema)
+	}
+
+	s.Test(`valid query`, func(t *testcase.T) {
+		q, err := parse(`status eq active`, `-created`)
+		require.Nil(t, err)
+		require.NotNil(t, q.Filter)
+		require.Equal(t, []gorest.SortField{{Field: `created`, Desc: true}}, q.Sort)
+	})
+
+	s.Test(`unknown filter field`, func(t *testcase.T) {
+		_, err := parse(`status eq active and secret eq 1`, ``)
+		require.Equal(t, &gorest.QueryError{Param: `filter`, Offset: 21, Msg: `field "secret" can't be filtered`}, err)
+	})
+
+	s.Test(`operator not allowed on the field`, func(t *testcase.T) {
+		_, err := parse(`status gt active`, ``)
+		require.Equal(t, &gorest.QueryError{Param: `filter`, Offset: 7, Msg: `operator "gt" is not allowed on field "status"`}, err)
+	})
+
+	s.Test(`field is not sortable`, func(t *testcase.T) {
+		_, err := parse(``, `status,note`)
+		require.Equal(t, &gorest.QueryError{Param: `sort`, Offset: 7, Msg: `field "note" can't be sorted`}, err)
+	})
+}
+
+func TestApplyListQuery(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	note := `urgent delivery`
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	orders := []Order{
+		{ID: 1, Status: `active`, Total: 10, Created: day(1)},
+		{ID: 2, Status: `closed`, Total: 30, Created: day(2), Note: &note},
+		{ID: 3, Status: `active`, Total: 20, Created: day(3)},
+		{ID: 4, Status: `active`, Total: 20, Created: day(4)},
+	}
+	var apply = func(t *testcase.T, filter, sort string) []int {
+		var q gorest.ListQuery
+		var err error
+		if filter != `` {
+			q.Filter, err = gorest.ParseFilter(filter)
+			require.Nil(t, err)
+		}
+		if sort != `` {
+			q.Sort, err = gorest.ParseSort(sort)
+			require.Nil(t, err)
+		}
+		result, err := gorest.ApplyListQuery(orders, q)
+		require.Nil(t, err)
+		var ids []int
+		for _, o := range result {
+			ids = append(ids, o.ID)
+		}
+		return ids
+	}
+
+	s.Test(`without query`, func(t *testcase.T) {
+		require.Equal(t, []int{1, 2, 3, 4}, apply(t, ``, ``))
+	})
+
+	s.Test(`filter`, func(t *testcase.T) {
+		require.Equal(t, []int{3, 4}, apply(t, `status eq "active" and created gt 2024-01-01 and total ge 20`, ``))
+		require.Equal(t, []int{1, 2}, apply(t, `total lt 15 or status ne active`, ``))
+		require.Equal(t, []int{2}, apply(t, `not (status eq active)`, ``))
+		require.Equal(t, []int{1, 3, 4}, apply(t, `note eq null`, ``))
+		require.Equal(t, []int{2}, apply(t, `note contains urgent`, ``))
+	})
+
+	s.Test(`sort`, func(t *testcase.T) {
+		require.Equal(t, []int{2, 4, 3, 1}, apply(t, ``, `-total,-created`))
+		require.Equal(t, []int{1, 3, 4, 2}, apply(t, ``, `status,id`))
+	})
+
+	s.Test(`nil pointer on the field path`, func(t *testcase.T) {
+		type Owner struct {
+			Name string `json:"name"`
+		}
+		type Repo struct {
+			ID    int    `json:"id"`
+			Owner *Owner `json:"owner"`
+		}
+		repos := []Repo{{ID: 1, Owner: &Owner{Name: `a`}}, {ID: 2}}
+		var apply = func(filter, sort string) []Repo {
+			var q gorest.ListQuery
+			q.Filter, _ = gorest.ParseFilter(filter)
+			q.Sort, _ = gorest.ParseSort(sort)
+			result, err := gorest.ApplyListQuery(repos, q)
+			require.Nil(t, err)
+			return result
+		}
+
+		require.Equal(t, []Repo{repos[0]}, apply(`owner.name eq "a"`, ``))
+		require.Equal(t, []Repo{repos[1]}, apply(`owner.name eq null`, ``))
+		require.Equal(t, []Repo{repos[1]}, apply(`owner.name ne "a"`, ``))
+		require.Equal(t, []Repo{repos[1], repos[0]}, apply(``, `owner.name`))
+		require.Equal(t, []Repo{repos[0], repos[1]}, apply(``, `-owner.name`))
+
+		q := gorest.ListQuery{}
+		q.Filter, _ = gorest.ParseFilter(`owner.phone eq "1"`)
+		_, err := gorest.ApplyListQuery(repos, q)
+		require.Equal(t, &gorest.QueryError{Param: `filter`, Offset: 0, Msg: `unknown field "owner.phone"`}, err)
+	})
+
+	s.Test(`value that doesn't fit the field`, func(t *testcase.T) {
+		q := gorest.ListQuery{}
+		q.Filter, _ = gorest.ParseFilter(`total gt many`)
+		_, err := gorest.ApplyListQuery(orders, q)
+		require.Equal(t, &gorest.QueryError{Param: `filter`, Offset: 9, Msg: `"many" is not a number`}, err)
+	})
+}
+
+type OrderController struct {
+	Orders []Order
+}
+
+func (ctrl OrderController) ListQuerySchema() gorest.QuerySchema { return orderQuerySchema }
+
+func (ctrl OrderController) List(w http.ResponseWriter, r *http.Request) {
+	q, _ := gorest.ListQueryFromContext(r.Context())
+	orders, err := gorest.ApplyListQuery(ctrl.Orders, q)
+	if err != nil {
+		gorest.WriteError(w, r, err)
+		return
+	}
+	_ = gorest.Encode(w, r, http.StatusOK, orders)
+}
+
+func TestWithListQuerySchema(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	var serve = func(t *testcase.T, query url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		gorest.NewHandler(OrderController{Orders: []Order{
+			{ID: 1, Status: `active`},
+			{ID: 2, Status: `closed`},
+		}}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/?`+query.Encode(), nil))
+		return w
+	}
+
+	s.Test(`the parsed query is passed to List`, func(t *testcase.T) {
+		resp := serve(t, url.Values{`filter`: {`status eq closed`}})
+		require.Equal(t, http.StatusOK, resp.Code)
+		var orders []Order
+		require.Nil(t, json.Unmarshal(resp.Body.Bytes(), &orders))
+		require.Len(t, orders, 1)
+		require.Equal(t, 2, orders[0].ID)
+	})
+
+	s.Test(`invalid query is rejected with its position`, func(t *testcase.T) {
+		resp := serve(t, url.Values{`filter`: {`status eq closed and id eq 1`}})
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), `gorest: invalid filter at offset 21: field "id" can't be filtered`)
+	})
+}
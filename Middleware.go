@@ -0,0 +1,88 @@
+package gorest
+
+import (
+	"context"
+	"net/http"
+)
+
+// Middleware wraps a http.Handler to extend its behaviour.
+type Middleware func(http.Handler) http.Handler
+
+type operationMiddleware struct {
+	ops        Operation
+	middleware Middleware
+}
+
+// Use registers middlewares that wrap every request the Handler serves.
+// They run before ContextWithResource, so they can't access the resource,
+// but they can reject a request before the resource is looked up.
+//
+// The middlewares are applied in the order of their registration, the first one being the outermost.
+func (h *Handler) Use(middlewares ...Middleware) {
+	h.middlewares = append(h.middlewares, middlewares...)
+
+	var next http.Handler = http.HandlerFunc(h.route)
+	for i := len(h.middlewares) - 1; 0 <= i; i-- {
+		next = h.middlewares[i](next)
+	}
+	h.chain = next
+}
+
+// UseFor registers middlewares that wrap only the selected operations.
+// They run after ContextWithResource, right before the operation,
+// so they can access the resource, and they can tell the operation with OperationFromContext.
+// The custom operations registered with Handle are selected with OpCustom.
+//
+// example:
+//
+//	h.UseFor(gorest.OpCreate|gorest.OpUpdate|gorest.OpDelete, AuditLog)
+//
+// The middlewares are applied in the order of their registration, the first one being the outermost.
+func (h *Handler) UseFor(ops Operation, middlewares ...Middleware) {
+	for _, mw := range middlewares {
+		h.operationMiddlewares = append(h.operationMiddlewares, operationMiddleware{ops: ops, middleware: mw})
+	}
+}
+
+// serveOperation serves the request with the operation when the Authorizer allows it
+// and the preconditions of the request hold, wrapped in the middlewares registered for it with UseFor.
+func (h *Handler) serveOperation(w http.ResponseWriter, r *http.Request, op operation) {
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeyOperation{}, op.kind))
+	if !h.authorize(w, r, op.kind) {
+		return
+	}
+	if !h.checkPreconditions(w, r, op.kind) {
+		return
+	}
+
+	handler := h.withListETag(op.kind, h.withFieldMask(op.kind, op.handler))
+	if h.Idempotency != nil && op.kind == OpCreate {
+		handler = Idempotent(h.Idempotency, h.IdempotencyScope)(handler)
+	}
+	for i := len(h.operationMiddlewares) - 1; 0 <= i; i-- {
+		if om := h.operationMiddlewares[i]; om.ops&op.kind != 0 {
+			handler = om.middleware(handler)
+		}
+	}
+	handler.ServeHTTP(w, r)
+}
+
+type ctxKeyOperation struct{}
+
+// OperationFromContext returns the operation that serves the request.
+// It is available for the middlewares registered with UseFor, and for the operation itself.
+func OperationFromContext(ctx context.Context) (Operation, bool) {
+	op, ok := ctx.Value(ctxKeyOperation{}).(Operation)
+	return op, ok
+}
+
+// customOperation is a http.Handler registered with Handler.Handle,
+// which is served as an OpCustom operation of the Handler.
+type customOperation struct {
+	handler http.Handler
+	owner   *Handler
+}
+
+func (co customOperation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	co.owner.serveOperation(w, r, operation{kind: OpCustom, handler: co.handler})
+}
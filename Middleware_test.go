@@ -0,0 +1,116 @@
+package gorest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestHandler_Use(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`log`, func(t *testcase.T) interface{} { return &[]string{} })
+	var log = func(t *testcase.T) []string { return *t.I(`log`).(*[]string) }
+	var record = func(t *testcase.T, entry string) {
+		ptr := t.I(`log`).(*[]string)
+		*ptr = append(*ptr, entry)
+	}
+	var middleware = func(t *testcase.T, name string) gorest.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				op, _ := gorest.OperationFromContext(r.Context())
+				record(t, name+`:`+op.String())
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		return gorest.NewHandler(StubController{
+			ListFunc: func(w http.ResponseWriter, r *http.Request) { record(t, `List`) },
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				record(t, `ContextWithResource`)
+				return context.WithValue(ctx, `id`, id), id == `42`, nil
+			},
+			ShowFunc:   func(w http.ResponseWriter, r *http.Request) { record(t, `Show`) },
+			UpdateFunc: func(w http.ResponseWriter, r *http.Request) { record(t, `Update`) },
+		})
+	})
+	var handler = func(t *testcase.T) *gorest.Handler { return t.I(`handler`).(*gorest.Handler) }
+	var serve = func(t *testcase.T, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(t).ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	s.Test(`Use middlewares wrap every request in the order of their registration`, func(t *testcase.T) {
+		handler(t).Use(middleware(t, `a`), middleware(t, `b`))
+		handler(t).Use(middleware(t, `c`))
+		serve(t, http.MethodGet, `/42`)
+		require.Equal(t, []string{`a:`, `b:`, `c:`, `ContextWithResource`, `Show`}, log(t))
+	})
+
+	s.Test(`Use middlewares can reject the request before the resource lookup`, func(t *testcase.T) {
+		handler(t).Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+		})
+		require.Equal(t, http.StatusUnauthorized, serve(t, http.MethodGet, `/42`).Code)
+		require.Empty(t, log(t))
+	})
+
+	s.Test(`Use middlewares wrap the requests that have no operation`, func(t *testcase.T) {
+		handler(t).Use(middleware(t, `a`))
+		require.Equal(t, http.StatusNotFound, serve(t, http.MethodGet, `/24`).Code)
+		require.Equal(t, []string{`a:`, `ContextWithResource`}, log(t))
+	})
+
+	s.Test(`UseFor middlewares wrap only the selected operations after the resource lookup`, func(t *testcase.T) {
+		handler(t).UseFor(gorest.OpShow|gorest.OpUpdate, middleware(t, `a`), middleware(t, `b`))
+		handler(t).UseFor(gorest.OpUpdate, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				record(t, `resource:`+r.Context().Value(`id`).(string))
+				next.ServeHTTP(w, r)
+			})
+		})
+
+		serve(t, http.MethodGet, `/`)
+		serve(t, http.MethodGet, `/42`)
+		serve(t, http.MethodPut, `/42`)
+		require.Equal(t, []string{
+			`List`,
+			`ContextWithResource`, `a:Show`, `b:Show`, `Show`,
+			`ContextWithResource`, `a:Update`, `b:Update`, `resource:42`, `Update`,
+		}, log(t))
+	})
+
+	s.Test(`Use middlewares run before the UseFor middlewares`, func(t *testcase.T) {
+		handler(t).UseFor(gorest.OpList, middleware(t, `op`))
+		handler(t).Use(middleware(t, `all`))
+		serve(t, http.MethodGet, `/`)
+		require.Equal(t, []string{`all:`, `op:List`, `List`}, log(t))
+	})
+
+	s.Test(`UseFor with OpCustom wraps the handlers registered with Handle`, func(t *testcase.T) {
+		handler(t).Handle(`/custom`, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, _ := gorest.OperationFromContext(r.Context())
+			record(t, `custom:`+op.String())
+		}))
+		gorest.Mount(handler(t), `/pets`, gorest.NewHandler(gorest.AsListController(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			record(t, `pets`)
+		}))))
+		handler(t).UseFor(gorest.OpCustom, middleware(t, `a`))
+
+		serve(t, http.MethodPost, `/42/custom`)
+		serve(t, http.MethodGet, `/42/pets/`)
+		require.Equal(t, `ContextWithResource,a:Custom,custom:Custom,ContextWithResource,pets`, strings.Join(log(t), `,`))
+	})
+}
@@ -19,7 +19,60 @@ type Multiplexer interface {
 func Mount(multiplexer Multiplexer, pattern string, handler http.Handler) {
 	pattern = `/` + strings.TrimPrefix(pattern, `/`)
 	pattern = strings.TrimSuffix(pattern, `/`)
-	h := http.StripPrefix(pattern, handler)
+	h := &mountedHandler{Handler: http.StripPrefix(pattern, handler), prefix: pattern, handler: handler}
 	multiplexer.Handle(pattern, h)
 	multiplexer.Handle(pattern+`/`, h)
+
+	// A Handler multiplexer dispatches the collection custom methods by itself,
+	// but other multiplexers need them registered one by one.
+	// The mount is remembered, so the custom methods registered after Mount are registered on the multiplexer as well.
+	if _, ok := multiplexer.(*Handler); ok {
+		return
+	}
+	if gh, ok := handler.(*Handler); ok {
+		m := collectionMount{multiplexer: multiplexer, pattern: pattern, handler: h}
+		gh.collectionMounts = append(gh.collectionMounts, m)
+		m.handle(gh, batchVerb)
+		for _, verb := range gh.collectionVerbs() {
+			if verb != batchVerb {
+				m.handle(gh, verb)
+			}
+		}
+	}
+}
+
+// collectionMount is a mount of a Handler on a multiplexer which is not a Handler.
+type collectionMount struct {
+	multiplexer Multiplexer
+	pattern     string
+	handler     http.Handler
+}
+
+// handle registers the collection custom method of the verb on the multiplexer.
+func (m collectionMount) handle(h *Handler, verb string) {
+	m.multiplexer.Handle(m.pattern+`:`+verb, collectionMethodHandler{owner: h, verb: verb, handler: m.handler})
+}
+
+// collectionMethodHandler serves a collection custom method that is registered on a multiplexer,
+// while the custom method exists, so the batch custom method follows the Batch field of the Handler.
+type collectionMethodHandler struct {
+	owner   *Handler
+	verb    string
+	handler http.Handler
+}
+
+func (c collectionMethodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := c.owner.lookupCustomMethod(CollectionScope, c.verb); !ok {
+		c.owner.notFound(w, r)
+		return
+	}
+	c.handler.ServeHTTP(w, r)
+}
+
+// mountedHandler is a prefix stripping http.Handler that keeps track of what it is wrapping,
+// so the routes of the mounted handler can be walked.
+type mountedHandler struct {
+	http.Handler
+	prefix  string
+	handler http.Handler
 }
@@ -0,0 +1,231 @@
+package gorest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification that the generated documents follow.
+const OpenAPIVersion = `3.0.3`
+
+// WithOpenAPIOperation is an optional controller interface to describe an operation in the generated OpenAPI document.
+// The received spec is prefilled with the path parameters and the standard responses,
+// and the controller can add its summary, request body, response schemas and examples to it.
+//
+// Custom operations registered with Handler.Handle can also implement it on their http.Handler.
+type WithOpenAPIOperation interface {
+	OpenAPIOperation(op Operation, method string, spec *OpenAPIOperation)
+}
+
+// WithOpenAPISchemas is an optional controller interface to register reusable schemas
+// under the components section of the generated OpenAPI document.
+// The schemas then can be referenced with OpenAPISchemaRef.
+type WithOpenAPISchemas interface {
+	OpenAPISchemas() map[string]*OpenAPISchema
+}
+
+// OpenAPIDocument is an OpenAPI 3 document.
+// It implements http.Handler, so the document can be served directly.
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi" yaml:"openapi"`
+	Info       OpenAPIInfo                `json:"info" yaml:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths" yaml:"paths"`
+	Components *OpenAPIComponents         `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version" yaml:"version"`
+}
+
+// OpenAPIPathItem holds the operations of a path by their lowercase http method name.
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                      `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses" yaml:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name" yaml:"name"`
+	In          string         `json:"in" yaml:"in"`
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool           `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+type OpenAPIRequestBody struct {
+	Description string                      `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool                        `json:"required,omitempty" yaml:"required,omitempty"`
+	Content     map[string]OpenAPIMediaType `json:"content" yaml:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description" yaml:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema  *OpenAPISchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Example interface{}    `json:"example,omitempty" yaml:"example,omitempty"`
+}
+
+type OpenAPISchema struct {
+	Ref         string                    `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type        string                    `json:"type,omitempty" yaml:"type,omitempty"`
+	Format      string                    `json:"format,omitempty" yaml:"format,omitempty"`
+	Description string                    `json:"description,omitempty" yaml:"description,omitempty"`
+	Properties  map[string]*OpenAPISchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Items       *OpenAPISchema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Required    []string                  `json:"required,omitempty" yaml:"required,omitempty"`
+	Enum        []interface{}             `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Example     interface{}               `json:"example,omitempty" yaml:"example,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+}
+
+// OpenAPISchemaRef returns a schema that references a schema registered with WithOpenAPISchemas.
+func OpenAPISchemaRef(name string) *OpenAPISchema {
+	return &OpenAPISchema{Ref: `#/components/schemas/` + name}
+}
+
+// NewOpenAPIDocument generates an OpenAPI document from the routes of the handler tree.
+// Custom operations that accept any http method are left out from the document,
+// as an OpenAPI operation must be bound to a http method.
+func NewOpenAPIDocument(handler http.Handler, info OpenAPIInfo) (*OpenAPIDocument, error) {
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]OpenAPIPathItem),
+	}
+
+	return doc, WalkRoutes(handler, func(route Route) error {
+		if route.Method == `` {
+			return nil
+		}
+
+		pathItem, ok := doc.Paths[route.Path]
+		if !ok {
+			pathItem = make(OpenAPIPathItem)
+			doc.Paths[route.Path] = pathItem
+		}
+		pathItem[strings.ToLower(route.Method)] = newOpenAPIOperation(route)
+
+		if i, ok := route.controller.(WithOpenAPISchemas); ok {
+			doc.addSchemas(i.OpenAPISchemas())
+		}
+		return nil
+	})
+}
+
+func (doc *OpenAPIDocument) addSchemas(schemas map[string]*OpenAPISchema) {
+	if len(schemas) == 0 {
+		return
+	}
+	if doc.Components == nil {
+		doc.Components = &OpenAPIComponents{}
+	}
+	if doc.Components.Schemas == nil {
+		doc.Components.Schemas = make(map[string]*OpenAPISchema)
+	}
+	for name, schema := range schemas {
+		doc.Components.Schemas[name] = schema
+	}
+}
+
+func newOpenAPIOperation(route Route) *OpenAPIOperation {
+	spec := &OpenAPIOperation{
+		OperationID: openAPIOperationID(route),
+		Summary:     route.Operation.String(),
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+
+	for _, param := range route.PathParams {
+		spec.Parameters = append(spec.Parameters, OpenAPIParameter{
+			Name:     param,
+			In:       `path`,
+			Required: true,
+			Schema:   &OpenAPISchema{Type: `string`},
+		})
+	}
+
+	codes := []int{http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusInternalServerError}
+	switch route.Operation {
+	case OpCreate:
+		codes = append(codes, http.StatusCreated)
+	case OpDelete:
+		codes = append(codes, http.StatusNoContent)
+	default:
+		codes = append(codes, http.StatusOK)
+	}
+	for _, code := range codes {
+		spec.Responses[strconv.Itoa(code)] = &OpenAPIResponse{Description: http.StatusText(code)}
+	}
+
+	if i, ok := route.controller.(WithOpenAPIOperation); ok {
+		i.OpenAPIOperation(route.Operation, route.Method, spec)
+	}
+	return spec
+}
+
+func openAPIOperationID(route Route) string {
+	id := strings.ToLower(route.Method) + route.Path
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, id), `_`)
+}
+
+// JSON returns the document in JSON format.
+func (doc *OpenAPIDocument) JSON() ([]byte, error) {
+	return json.MarshalIndent(doc, ``, `  `)
+}
+
+// YAML returns the document in YAML format.
+func (doc *OpenAPIDocument) YAML() ([]byte, error) {
+	return yaml.Marshal(doc)
+}
+
+// ServeHTTP serves the document in JSON format,
+// or in YAML format when the requester accepts YAML or the path has a .yaml or .yml extension.
+func (doc *OpenAPIDocument) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		bs          []byte
+		err         error
+		contentType string
+	)
+	if strings.Contains(r.Header.Get(`Accept`), `yaml`) ||
+		strings.HasSuffix(r.URL.Path, `.yaml`) ||
+		strings.HasSuffix(r.URL.Path, `.yml`) {
+		bs, err = doc.YAML()
+		contentType = `application/yaml`
+	} else {
+		bs, err = doc.JSON()
+		contentType = `application/json`
+	}
+	if err != nil {
+		const code = http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, contentType)
+	w.Header().Set(`Content-Length`, strconv.Itoa(len(bs)))
+	_, _ = w.Write(bs)
+}
@@ -0,0 +1,119 @@
+package gorest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/adamluzsi/gorest"
+)
+
+type OpenAPIController struct{ StubController }
+
+func (ctrl OpenAPIController) OpenAPIOperation(op gorest.Operation, method string, spec *gorest.OpenAPIOperation) {
+	if op != gorest.OpShow {
+		return
+	}
+	spec.Summary = `Show a user`
+	spec.Responses[`200`].Content = map[string]gorest.OpenAPIMediaType{
+		`application/json`: {
+			Schema:  gorest.OpenAPISchemaRef(`User`),
+			Example: map[string]interface{}{`id`: `42`},
+		},
+	}
+}
+
+func (ctrl OpenAPIController) OpenAPISchemas() map[string]*gorest.OpenAPISchema {
+	return map[string]*gorest.OpenAPISchema{
+		`User`: {Type: `object`, Properties: map[string]*gorest.OpenAPISchema{`id`: {Type: `string`}}},
+	}
+}
+
+func TestNewOpenAPIDocument(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		users := gorest.Resource(`users`, OpenAPIController{})
+		users.Sub(`contacts`, gorest.AsListController(http.NotFoundHandler()))
+		h, err := users.Build()
+		require.Nil(t, err)
+		return h
+	})
+
+	var subject = func(t *testcase.T) *gorest.OpenAPIDocument {
+		doc, err := gorest.NewOpenAPIDocument(t.I(`handler`).(http.Handler), gorest.OpenAPIInfo{Title: `test`, Version: `1.0.0`})
+		require.Nil(t, err)
+		return doc
+	}
+
+	s.Then(`every route is described`, func(t *testcase.T) {
+		doc := subject(t)
+		require.Equal(t, gorest.OpenAPIVersion, doc.OpenAPI)
+		require.Contains(t, doc.Paths, `/users`)
+		require.Contains(t, doc.Paths, `/users/{users-id}`)
+		require.Contains(t, doc.Paths, `/users/{users-id}/contacts`)
+		require.Contains(t, doc.Paths[`/users`], `get`)
+		require.Contains(t, doc.Paths[`/users`], `post`)
+		require.Contains(t, doc.Paths[`/users/{users-id}`], `delete`)
+	})
+
+	s.Then(`the path parameters are listed for each unshifted resource id`, func(t *testcase.T) {
+		op := subject(t).Paths[`/users/{users-id}/contacts`][`get`]
+		require.Len(t, op.Parameters, 1)
+		require.Equal(t, `users-id`, op.Parameters[0].Name)
+		require.Equal(t, `path`, op.Parameters[0].In)
+		require.True(t, op.Parameters[0].Required)
+	})
+
+	s.Then(`the standard responses are included`, func(t *testcase.T) {
+		doc := subject(t)
+		op := doc.Paths[`/users`][`post`]
+		for _, code := range []string{`201`, `404`, `405`, `500`} {
+			require.Contains(t, op.Responses, code)
+		}
+		require.Contains(t, doc.Paths[`/users/{users-id}`][`delete`].Responses, `204`)
+	})
+
+	s.Then(`the controller can describe its operations and schemas`, func(t *testcase.T) {
+		doc := subject(t)
+		op := doc.Paths[`/users/{users-id}`][`get`]
+		require.Equal(t, `Show a user`, op.Summary)
+		require.Equal(t, `#/components/schemas/User`, op.Responses[`200`].Content[`application/json`].Schema.Ref)
+		require.Contains(t, doc.Components.Schemas, `User`)
+	})
+
+	s.Then(`it can be encoded as JSON and YAML`, func(t *testcase.T) {
+		doc := subject(t)
+
+		bs, err := doc.JSON()
+		require.Nil(t, err)
+		var fromJSON map[string]interface{}
+		require.Nil(t, json.Unmarshal(bs, &fromJSON))
+		require.Equal(t, gorest.OpenAPIVersion, fromJSON[`openapi`])
+
+		bs, err = doc.YAML()
+		require.Nil(t, err)
+		var fromYAML map[string]interface{}
+		require.Nil(t, yaml.Unmarshal(bs, &fromYAML))
+		require.Equal(t, gorest.OpenAPIVersion, fromYAML[`openapi`])
+	})
+
+	s.Then(`it can be served as a http.Handler`, func(t *testcase.T) {
+		doc := subject(t)
+
+		w := httptest.NewRecorder()
+		doc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/openapi.json`, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `application/json`, w.Header().Get(`Content-Type`))
+
+		w = httptest.NewRecorder()
+		doc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/openapi.yaml`, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `application/yaml`, w.Header().Get(`Content-Type`))
+	})
+}
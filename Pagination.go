@@ -0,0 +1,291 @@
+package gorest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PaginationMode tells how the pages of a collection are addressed.
+type PaginationMode int
+
+const (
+	// OffsetPagination addresses the pages with the offset or page and limit query parameters.
+	OffsetPagination PaginationMode = iota
+	// CursorPagination addresses the pages with the cursor and limit query parameters.
+	CursorPagination
+)
+
+const (
+	defaultPaginationLimit    = 20
+	defaultPaginationMaxLimit = 100
+)
+
+// Pagination parses the page requests of a collection, and describes the served pages with RFC 8288 Link headers.
+//
+// In offset mode the accepted query parameters are limit, and either offset or the 1-based page.
+// In cursor mode the accepted query parameters are limit and cursor.
+//
+// The zero value is ready to use in offset mode.
+type Pagination struct {
+	Mode PaginationMode
+	// DefaultLimit is the page size when the request doesn't specify one. It defaults to 20.
+	DefaultLimit int
+	// MaxLimit is the largest page size that can be requested. It defaults to 100.
+	MaxLimit int
+	// Secret signs the cursors, so the requester can't forge them.
+	// When it is empty, the cursors are only encoded.
+	Secret []byte
+	// TotalCountHeader is the name of the response header that reports the total number of items, like X-Total-Count.
+	// The header is written only when it is set and the Page knows the total.
+	TotalCountHeader string
+}
+
+// PageRequest is a page that the requester asked for.
+type PageRequest struct {
+	Limit int
+	// Offset is the number of items to skip in offset mode.
+	Offset int
+	// Cursor is the decoded cursor value in cursor mode.
+	// It is empty for the first page.
+	Cursor string
+}
+
+// Page is a page of a collection.
+type Page struct {
+	// Items are the items of the page, and they are encoded as the response body.
+	Items interface{}
+	// HasNext reports in offset mode that there are more items after the page.
+	HasNext bool
+	// NextCursor is the cursor value of the next page in cursor mode.
+	// When it is empty, the page is the last one.
+	NextCursor string
+	// PrevCursor is the cursor value of the previous page in cursor mode.
+	// When it is empty, the page has no previous page.
+	PrevCursor string
+	// Total is the total number of items in the collection, when it is known.
+	Total *int
+}
+
+// PaginatedListController is a variant of ListController that returns the page of the collection,
+// and leaves the parsing of the page request and the encoding of the response to the Handler.
+// The Pagination of the Handler is used to parse the page request.
+type PaginatedListController interface {
+	ListPage(r *http.Request, page PageRequest) (Page, error)
+}
+
+const (
+	paginationLimitParam  = `limit`
+	paginationOffsetParam = `offset`
+	paginationPageParam   = `page`
+	paginationCursorParam = `cursor`
+)
+
+// Parse parses and validates the page request.
+// The returned error is a *QueryError when the page parameters are invalid.
+// An offset or page that would overflow the paging arithmetic is invalid as well.
+func (p Pagination) Parse(r *http.Request) (PageRequest, error) {
+	query := r.URL.Query()
+	req := PageRequest{Limit: p.defaultLimit()}
+
+	if raw := query.Get(paginationLimitParam); raw != `` {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || p.maxLimit() < limit {
+			return PageRequest{}, &QueryError{Param: paginationLimitParam, Msg: fmt.Sprintf(`must be between 1 and %d`, p.maxLimit())}
+		}
+		req.Limit = limit
+	}
+
+	switch p.Mode {
+	case CursorPagination:
+		for _, param := range []string{paginationOffsetParam, paginationPageParam} {
+			if query.Has(param) {
+				return PageRequest{}, &QueryError{Param: param, Msg: `is not supported, use cursor`}
+			}
+		}
+		if raw := query.Get(paginationCursorParam); raw != `` {
+			cursor, err := p.DecodeCursor(raw)
+			if err != nil {
+				return PageRequest{}, err
+			}
+			req.Cursor = cursor
+		}
+
+	default:
+		if query.Has(paginationCursorParam) {
+			return PageRequest{}, &QueryError{Param: paginationCursorParam, Msg: `is not supported, use offset`}
+		}
+		if query.Has(paginationOffsetParam) && query.Has(paginationPageParam) {
+			return PageRequest{}, &QueryError{Param: paginationPageParam, Msg: `can't be used together with offset`}
+		}
+		if raw := query.Get(paginationOffsetParam); raw != `` {
+			offset, err := strconv.Atoi(raw)
+			if err != nil || offset < 0 {
+				return PageRequest{}, &QueryError{Param: paginationOffsetParam, Msg: `must be a non-negative integer`}
+			}
+			if math.MaxInt-req.Limit < offset {
+				return PageRequest{}, &QueryError{Param: paginationOffsetParam, Msg: `is too large`}
+			}
+			req.Offset = offset
+		}
+		if raw := query.Get(paginationPageParam); raw != `` {
+			page, err := strconv.Atoi(raw)
+			if err != nil || page < 1 {
+				return PageRequest{}, &QueryError{Param: paginationPageParam, Msg: `must be a positive integer`}
+			}
+			if (math.MaxInt-req.Limit)/req.Limit < page-1 {
+				return PageRequest{}, &QueryError{Param: paginationPageParam, Msg: `is too large`}
+			}
+			req.Offset = (page - 1) * req.Limit
+		}
+	}
+
+	return req, nil
+}
+
+// WriteHeaders writes the Link header with the first, prev and next pages,
+// and the total count header when it is configured.
+// The links are built from the request URI, so they point to the collection as the requester sees it.
+func (p Pagination) WriteHeaders(w http.ResponseWriter, r *http.Request, req PageRequest, page Page) {
+	var links []string
+	link := func(rel string, params map[string]string) {
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, pageURL(r, params), rel))
+	}
+
+	limit := strconv.Itoa(req.Limit)
+	switch p.Mode {
+	case CursorPagination:
+		link(`first`, map[string]string{paginationLimitParam: limit})
+		if page.PrevCursor != `` {
+			link(`prev`, map[string]string{paginationLimitParam: limit, paginationCursorParam: p.EncodeCursor(page.PrevCursor)})
+		}
+		if page.NextCursor != `` {
+			link(`next`, map[string]string{paginationLimitParam: limit, paginationCursorParam: p.EncodeCursor(page.NextCursor)})
+		}
+
+	default:
+		offset := func(n int) map[string]string {
+			return map[string]string{paginationLimitParam: limit, paginationOffsetParam: strconv.Itoa(n)}
+		}
+		link(`first`, offset(0))
+		if 0 < req.Offset {
+			prev := req.Offset - req.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			link(`prev`, offset(prev))
+		}
+		if page.HasNext || (page.Total != nil && req.Offset+req.Limit < *page.Total) {
+			link(`next`, offset(req.Offset+req.Limit))
+		}
+	}
+
+	w.Header().Set(`Link`, strings.Join(links, `, `))
+	if p.TotalCountHeader != `` && page.Total != nil {
+		w.Header().Set(p.TotalCountHeader, strconv.Itoa(*page.Total))
+	}
+}
+
+// EncodeCursor encodes a cursor value into an opaque cursor, signed when the Pagination has a Secret.
+func (p Pagination) EncodeCursor(value string) string {
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(value))
+	if len(p.Secret) == 0 {
+		return cursor
+	}
+	return cursor + `.` + base64.RawURLEncoding.EncodeToString(p.sign(cursor))
+}
+
+// DecodeCursor decodes a cursor created by EncodeCursor, and verifies its signature.
+// The returned error is a *QueryError when the cursor is invalid.
+func (p Pagination) DecodeCursor(cursor string) (string, error) {
+	invalid := &QueryError{Param: paginationCursorParam, Msg: `is invalid`}
+
+	if len(p.Secret) != 0 {
+		i := strings.LastIndexByte(cursor, '.')
+		if i < 0 {
+			return ``, invalid
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(cursor[i+1:])
+		if err != nil || !hmac.Equal(sig, p.sign(cursor[:i])) {
+			return ``, invalid
+		}
+		cursor = cursor[:i]
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ``, invalid
+	}
+	return string(value), nil
+}
+
+func (p Pagination) sign(cursor string) []byte {
+	mac := hmac.New(sha256.New, p.Secret)
+	_, _ = mac.Write([]byte(cursor))
+	return mac.Sum(nil)
+}
+
+func (p Pagination) defaultLimit() int {
+	if p.DefaultLimit <= 0 {
+		return defaultPaginationLimit
+	}
+	return p.DefaultLimit
+}
+
+func (p Pagination) maxLimit() int {
+	if p.MaxLimit <= 0 {
+		return defaultPaginationMaxLimit
+	}
+	return p.MaxLimit
+}
+
+// pageURL is the request URI with the page parameters replaced.
+func pageURL(r *http.Request, params map[string]string) string {
+	u, err := url.ParseRequestURI(requestURI(r))
+	if err != nil {
+		u = &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	}
+	query := u.Query()
+	for _, name := range []string{paginationLimitParam, paginationOffsetParam, paginationPageParam, paginationCursorParam} {
+		query.Del(name)
+	}
+	for name, value := range params {
+		query.Set(name, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// paginatedList serves the List operation of a PaginatedListController.
+type paginatedList struct {
+	handler    *Handler
+	controller PaginatedListController
+}
+
+func (pl paginatedList) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var p Pagination
+	if pl.handler.Pagination != nil {
+		p = *pl.handler.Pagination
+	}
+
+	req, err := p.Parse(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	page, err := pl.controller.ListPage(r, req)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	p.WriteHeaders(w, r, req, page)
+	_ = Encode(w, r, http.StatusOK, page.Items)
+}
@@ -0,0 +1,190 @@
+package gorest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestPagination_Parse(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`pagination`, func(t *testcase.T) interface{} { return gorest.Pagination{MaxLimit: 50} })
+	var parse = func(t *testcase.T, target string) (gorest.PageRequest, error) {
+		return t.I(`pagination`).(gorest.Pagination).Parse(httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	s.Test(`defaults`, func(t *testcase.T) {
+		req, err := parse(t, `/`)
+		require.Nil(t, err)
+		require.Equal(t, gorest.PageRequest{Limit: 20}, req)
+	})
+
+	s.Test(`offset and limit`, func(t *testcase.T) {
+		req, err := parse(t, `/?limit=10&offset=30`)
+		require.Nil(t, err)
+		require.Equal(t, gorest.PageRequest{Limit: 10, Offset: 30}, req)
+	})
+
+	s.Test(`page is converted to offset`, func(t *testcase.T) {
+		req, err := parse(t, `/?limit=10&page=3`)
+		require.Nil(t, err)
+		require.Equal(t, gorest.PageRequest{Limit: 10, Offset: 20}, req)
+	})
+
+	for _, target := range []string{
+		`/?limit=0`,
+		`/?limit=51`,
+		`/?limit=abc`,
+		`/?offset=-1`,
+		`/?page=0`,
+		`/?offset=1&page=1`,
+		`/?cursor=abc`,
+		`/?offset=` + strconv.Itoa(math.MaxInt),
+		`/?limit=50&page=` + strconv.Itoa(math.MaxInt/25),
+	} {
+		target := target
+		s.Test(`invalid request: `+target, func(t *testcase.T) {
+			_, err := parse(t, target)
+			require.True(t, errors.Is(err, gorest.ErrBadRequest))
+			var qerr *gorest.QueryError
+			require.True(t, errors.As(err, &qerr))
+		})
+	}
+
+	s.When(`cursor mode is used`, func(s *testcase.Spec) {
+		s.Let(`pagination`, func(t *testcase.T) interface{} {
+			return gorest.Pagination{Mode: gorest.CursorPagination, Secret: []byte(`secret`)}
+		})
+
+		s.Then(`the cursor is decoded`, func(t *testcase.T) {
+			cursor := t.I(`pagination`).(gorest.Pagination).EncodeCursor(`id:42`)
+			req, err := parse(t, `/?limit=5&cursor=`+cursor)
+			require.Nil(t, err)
+			require.Equal(t, gorest.PageRequest{Limit: 5, Cursor: `id:42`}, req)
+		})
+
+		s.Then(`forged cursors are rejected`, func(t *testcase.T) {
+			forged := gorest.Pagination{Secret: []byte(`other`)}.EncodeCursor(`id:42`)
+			_, err := parse(t, `/?cursor=`+forged)
+			require.True(t, errors.Is(err, gorest.ErrBadRequest))
+
+			_, err = parse(t, `/?cursor=`+gorest.Pagination{}.EncodeCursor(`id:42`))
+			require.True(t, errors.Is(err, gorest.ErrBadRequest))
+		})
+
+		s.Then(`offset is rejected`, func(t *testcase.T) {
+			_, err := parse(t, `/?offset=10`)
+			require.True(t, errors.Is(err, gorest.ErrBadRequest))
+		})
+	})
+}
+
+type PaginatedTeapots struct {
+	Total   int
+	Err     error
+	Request *gorest.PageRequest
+}
+
+func (ctrl PaginatedTeapots) ListPage(r *http.Request, req gorest.PageRequest) (gorest.Page, error) {
+	*ctrl.Request = req
+	if ctrl.Err != nil {
+		return gorest.Page{}, ctrl.Err
+	}
+
+	var items []int
+	if req.Cursor != `` {
+		start, _ := strconv.Atoi(req.Cursor)
+		for i := start; i < start+req.Limit && i < ctrl.Total; i++ {
+			items = append(items, i)
+		}
+		page := gorest.Page{Items: items, PrevCursor: strconv.Itoa(start - req.Limit)}
+		if start+req.Limit < ctrl.Total {
+			page.NextCursor = strconv.Itoa(start + req.Limit)
+		}
+		return page, nil
+	}
+
+	for i := req.Offset; i < req.Offset+req.Limit && i < ctrl.Total; i++ {
+		items = append(items, i)
+	}
+	return gorest.Page{Items: items, Total: &ctrl.Total}, nil
+}
+
+func TestPaginatedListController(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`request`, func(t *testcase.T) interface{} { return &gorest.PageRequest{} })
+	s.Let(`controller`, func(t *testcase.T) interface{} {
+		return PaginatedTeapots{Total: 25, Request: t.I(`request`).(*gorest.PageRequest)}
+	})
+	s.Let(`pagination`, func(t *testcase.T) interface{} {
+		return &gorest.Pagination{DefaultLimit: 10, TotalCountHeader: `X-Total-Count`}
+	})
+	var serve = func(t *testcase.T, target string) *httptest.ResponseRecorder {
+		h := gorest.NewHandler(t.I(`controller`))
+		h.Pagination = t.I(`pagination`).(*gorest.Pagination)
+		mux := http.NewServeMux()
+		gorest.Mount(mux, `/teapots`, h)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	s.Test(`the first page`, func(t *testcase.T) {
+		resp := serve(t, `/teapots/?sort=name`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `25`, resp.Header().Get(`X-Total-Count`))
+		require.Equal(t, `</teapots/?limit=10&offset=0&sort=name>; rel="first", </teapots/?limit=10&offset=10&sort=name>; rel="next"`, resp.Header().Get(`Link`))
+
+		var items []int
+		require.Nil(t, json.Unmarshal(resp.Body.Bytes(), &items))
+		require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, items)
+	})
+
+	s.Test(`the last page`, func(t *testcase.T) {
+		resp := serve(t, `/teapots/?page=3`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, gorest.PageRequest{Limit: 10, Offset: 20}, *t.I(`request`).(*gorest.PageRequest))
+		require.Equal(t, `</teapots/?limit=10&offset=0>; rel="first", </teapots/?limit=10&offset=10>; rel="prev"`, resp.Header().Get(`Link`))
+	})
+
+	s.Test(`invalid page request`, func(t *testcase.T) {
+		resp := serve(t, `/teapots/?limit=1000`)
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), `limit`)
+		require.Contains(t, resp.Body.String(), `must be between 1 and 100`)
+	})
+
+	s.Test(`controller error`, func(t *testcase.T) {
+		t.Let(`controller`, PaginatedTeapots{Err: gorest.ErrForbidden, Request: t.I(`request`).(*gorest.PageRequest)})
+		require.Equal(t, http.StatusForbidden, serve(t, `/teapots/`).Code)
+	})
+
+	s.When(`cursor mode is used`, func(s *testcase.Spec) {
+		s.Let(`pagination`, func(t *testcase.T) interface{} {
+			return &gorest.Pagination{Mode: gorest.CursorPagination, DefaultLimit: 10, Secret: []byte(`secret`)}
+		})
+
+		s.Then(`the links carry the signed cursors`, func(t *testcase.T) {
+			p := t.I(`pagination`).(*gorest.Pagination)
+			resp := serve(t, `/teapots/?cursor=`+p.EncodeCursor(`10`))
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Empty(t, resp.Header().Get(`X-Total-Count`))
+			require.Equal(t, `</teapots/?limit=10>; rel="first", `+
+				`</teapots/?cursor=`+p.EncodeCursor(`0`)+`&limit=10>; rel="prev", `+
+				`</teapots/?cursor=`+p.EncodeCursor(`20`)+`&limit=10>; rel="next"`,
+				resp.Header().Get(`Link`))
+		})
+	})
+}
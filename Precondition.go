@@ -0,0 +1,233 @@
+package gorest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Versioned is implemented by resources that expose their current version,
+// so the Handler can use it as the entity tag of the resource.
+//
+// When the resource stored with WithResource is Versioned,
+// the Handler sets the ETag header on Show responses, and answers Show with 304 Not Modified on a matching If-None-Match,
+// while Update and Delete are only served when the If-Match header of the request matches the current version.
+type Versioned interface {
+	// Version returns the entity tag of the current state of the resource, like "v42" or W/"v42".
+	// An unquoted version is quoted by the Handler.
+	Version() string
+}
+
+// Validators is implemented by resources that expose the validators of their current representation.
+// It takes precedence over Versioned.
+//
+// When the resource stored with WithResource has Validators,
+// the Handler sets the ETag and Last-Modified headers on Show responses,
+// and answers the requests with a matching If-None-Match or If-Modified-Since header with 304 Not Modified,
+// without calling Show.
+// The entity tag is also used to check the If-Match header of Update and Delete, like with Versioned.
+type Validators interface {
+	// Validators returns the entity tag and the last modification time of the current state of the resource.
+	// Either of them can be left empty. An unquoted entity tag is quoted by the Handler.
+	Validators() (etag string, lastModified time.Time)
+}
+
+type ctxKeyResource struct{}
+
+// ctxKeyResourceOwner holds the Handler that is looking up the resource of the request.
+type ctxKeyResourceOwner struct{}
+
+// resourceValue is a resource stored with WithResource, and the Handler whose ContextHandler stored it.
+type resourceValue struct {
+	resource interface{}
+	owner    *Handler
+}
+
+// WithResource returns a copy of the context that holds the resource loaded by ContextWithResource.
+// A ContextHandler uses it to share the resource with the Handler, which checks the preconditions with it.
+// The resource only applies to the preconditions of the Handler whose ContextHandler stored it,
+// so a nested Handler never checks its requests against the resource of an outer Handler.
+func WithResource(ctx context.Context, resource interface{}) context.Context {
+	owner, _ := ctx.Value(ctxKeyResourceOwner{}).(*Handler)
+	return context.WithValue(ctx, ctxKeyResource{}, resourceValue{resource: resource, owner: owner})
+}
+
+// ResourceFromContext returns the resource stored with WithResource.
+// Under nested Handlers, it is the resource of the most inner Handler that stored one.
+func ResourceFromContext(ctx context.Context) (interface{}, bool) {
+	v, ok := ctx.Value(ctxKeyResource{}).(resourceValue)
+	return v.resource, ok && v.resource != nil
+}
+
+// resourceValidators returns the validators of the resource that the ContextHandler of the Handler stored in the context,
+// when it implements Versioned or Validators.
+func (h *Handler) resourceValidators(ctx context.Context) (etag string, lastModified time.Time, ok bool) {
+	v, ok := ctx.Value(ctxKeyResource{}).(resourceValue)
+	if !ok || v.owner != h {
+		return ``, time.Time{}, false
+	}
+	switch v := v.resource.(type) {
+	case Validators:
+		etag, lastModified = v.Validators()
+	case Versioned:
+		etag = v.Version()
+	default:
+		return ``, time.Time{}, false
+	}
+	if etag != `` && !strings.HasSuffix(etag, `"`) {
+		etag = `"` + etag + `"`
+	}
+	return etag, lastModified, true
+}
+
+// checkPreconditions evaluates the conditional headers of the request against the validators of the resource.
+// It reports whether the operation can be served, and when it can't, the response is already replied.
+func (h *Handler) checkPreconditions(w http.ResponseWriter, r *http.Request, op Operation) bool {
+	etag, lastModified, ok := h.resourceValidators(r.Context())
+	if !ok {
+		return true
+	}
+
+	switch op {
+	case OpShow:
+		if etag != `` {
+			w.Header().Set(`ETag`, etag)
+		}
+		if !lastModified.IsZero() {
+			w.Header().Set(`Last-Modified`, lastModified.UTC().Format(http.TimeFormat))
+		}
+		if isNotModified(r, etag, lastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return false
+		}
+		return true
+
+	case OpUpdate, OpDelete:
+		ifMatch := r.Header.Values(`If-Match`)
+		if len(ifMatch) == 0 {
+			if h.RequireIfMatch {
+				h.handleError(w, r, ErrPreconditionRequired)
+				return false
+			}
+			return true
+		}
+		if !matchesEntityTag(ifMatch, etag, false) {
+			h.handleError(w, r, ErrPreconditionFailed)
+			return false
+		}
+		return true
+
+	default:
+		return true
+	}
+}
+
+// isNotModified reports whether the representation the requester has is still the current one.
+// If-Modified-Since is only considered when the request has no If-None-Match header.
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if ifNoneMatch := r.Header.Values(`If-None-Match`); len(ifNoneMatch) != 0 {
+		return etag != `` && matchesEntityTag(ifNoneMatch, etag, true)
+	}
+	ifModifiedSince := r.Header.Get(`If-Modified-Since`)
+	if ifModifiedSince == `` || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// matchesEntityTag reports whether a conditional header matches the entity tag.
+// If-Match uses the strong comparison, where weak entity tags never match,
+// while If-None-Match uses the weak comparison, where the weakness indicators are ignored.
+func matchesEntityTag(header []string, etag string, weak bool) bool {
+	if !weak && strings.HasPrefix(etag, `W/`) {
+		return false
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, `W/`)
+	}
+	for _, value := range header {
+		for _, candidate := range strings.Split(value, `,`) {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == `*` {
+				return true
+			}
+			if weak {
+				candidate = strings.TrimPrefix(candidate, `W/`)
+			}
+			if candidate != `` && candidate == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withListETag buffers the successful GET responses of the List operation when ListETags is enabled,
+// so their weak entity tag can be computed from their body.
+func (h *Handler) withListETag(kind Operation, next http.Handler) http.Handler {
+	if !h.ListETags || kind != OpList {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ew := &listETagResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, r)
+		ew.finish(r)
+	})
+}
+
+type listETagResponseWriter struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (w *listETagResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *listETagResponseWriter) Write(bs []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(bs)
+}
+
+func (w *listETagResponseWriter) finish(r *http.Request) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	if w.code == http.StatusOK && w.Header().Get(`ETag`) == `` {
+		sum := sha256.Sum256(w.body.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set(`ETag`, etag)
+
+		if ifNoneMatch := r.Header.Values(`If-None-Match`); len(ifNoneMatch) != 0 && matchesEntityTag(ifNoneMatch, etag, true) {
+			w.Header().Del(`Content-Length`)
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
@@ -0,0 +1,304 @@
+package gorest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+type VersionedDocument struct{ Rev string }
+
+func (d VersionedDocument) Version() string { return d.Rev }
+
+type ValidatedDocument struct {
+	ETag     string
+	Modified time.Time
+}
+
+func (d ValidatedDocument) Validators() (string, time.Time) { return d.ETag, d.Modified }
+
+func TestHandler_preconditions(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`resource`, func(t *testcase.T) interface{} { return VersionedDocument{Rev: `v2`} })
+	s.Let(`require if-match`, func(t *testcase.T) interface{} { return false })
+	s.Let(`served`, func(t *testcase.T) interface{} { return new(bool) })
+	var served = func(t *testcase.T) bool { return *t.I(`served`).(*bool) }
+
+	var serve = func(t *testcase.T, method string, header http.Header) *httptest.ResponseRecorder {
+		markServed := func(w http.ResponseWriter, r *http.Request) { *t.I(`served`).(*bool) = true }
+		h := gorest.NewHandler(StubController{
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				return gorest.WithResource(ctx, t.I(`resource`)), true, nil
+			},
+			ShowFunc:   markServed,
+			UpdateFunc: markServed,
+			DeleteFunc: markServed,
+		})
+		h.RequireIfMatch = t.I(`require if-match`).(bool)
+		r := httptest.NewRequest(method, `/42`, nil)
+		for k, vs := range header {
+			r.Header[k] = vs
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	s.Test(`Show responses have the ETag of the resource`, func(t *testcase.T) {
+		resp := serve(t, http.MethodGet, nil)
+		require.Equal(t, `"v2"`, resp.Header().Get(`ETag`))
+		require.True(t, served(t))
+	})
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		method := method
+
+		s.Describe(method, func(s *testcase.Spec) {
+			s.Then(`matching If-Match is served`, func(t *testcase.T) {
+				serve(t, method, http.Header{`If-Match`: {`"v1", "v2"`}})
+				require.True(t, served(t))
+			})
+
+			s.Then(`wildcard If-Match is served`, func(t *testcase.T) {
+				serve(t, method, http.Header{`If-Match`: {`*`}})
+				require.True(t, served(t))
+			})
+
+			s.Then(`stale If-Match is rejected with 412`, func(t *testcase.T) {
+				resp := serve(t, method, http.Header{`If-Match`: {`"v1"`}})
+				require.Equal(t, http.StatusPreconditionFailed, resp.Code)
+				require.False(t, served(t))
+			})
+
+			s.Then(`missing If-Match is served`, func(t *testcase.T) {
+				serve(t, method, nil)
+				require.True(t, served(t))
+			})
+
+			s.And(`If-Match is required`, func(s *testcase.Spec) {
+				s.Let(`require if-match`, func(t *testcase.T) interface{} { return true })
+
+				s.Then(`missing If-Match is rejected with 428`, func(t *testcase.T) {
+					resp := serve(t, method, nil)
+					require.Equal(t, http.StatusPreconditionRequired, resp.Code)
+					require.False(t, served(t))
+				})
+			})
+		})
+	}
+
+	s.When(`the version is a weak entity tag`, func(s *testcase.Spec) {
+		s.Let(`resource`, func(t *testcase.T) interface{} { return VersionedDocument{Rev: `W/"v2"`} })
+
+		s.Then(`it is never matched by If-Match`, func(t *testcase.T) {
+			require.Equal(t, http.StatusPreconditionFailed, serve(t, http.MethodPut, http.Header{`If-Match`: {`W/"v2"`}}).Code)
+		})
+	})
+
+	s.When(`the resource is not versioned`, func(s *testcase.Spec) {
+		s.Let(`resource`, func(t *testcase.T) interface{} { return `plain` })
+		s.Let(`require if-match`, func(t *testcase.T) interface{} { return true })
+
+		s.Then(`the preconditions are not checked`, func(t *testcase.T) {
+			resp := serve(t, http.MethodPut, http.Header{`If-Match`: {`"v1"`}})
+			require.True(t, served(t))
+			require.Empty(t, resp.Header().Get(`ETag`))
+		})
+	})
+	s.When(`a nested Handler has no resource of its own`, func(s *testcase.Spec) {
+		var serveChild = func(t *testcase.T, method string, header http.Header) *httptest.ResponseRecorder {
+			markServed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { *t.I(`served`).(*bool) = true })
+			parent := gorest.NewHandler(StubController{
+				ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+					return gorest.WithResource(ctx, VersionedDocument{Rev: `parent-v1`}), true, nil
+				},
+			})
+			parent.RequireIfMatch = true
+			child := gorest.NewHandler(struct {
+				gorest.ShowController
+				gorest.UpdateController
+			}{
+				ShowController:   gorest.AsShowController(markServed),
+				UpdateController: gorest.AsUpdateController(markServed),
+			})
+			child.RequireIfMatch = true
+			gorest.Mount(parent, `/children`, child)
+
+			r := httptest.NewRequest(method, `/1/children/7`, nil)
+			for k, vs := range header {
+				r.Header[k] = vs
+			}
+			w := httptest.NewRecorder()
+			parent.ServeHTTP(w, r)
+			return w
+		}
+
+		s.Then(`Show has no ETag of the outer resource`, func(t *testcase.T) {
+			resp := serveChild(t, http.MethodGet, nil)
+			require.Empty(t, resp.Header().Get(`ETag`))
+			require.True(t, served(t))
+		})
+
+		s.Then(`If-Match is not checked against the outer resource`, func(t *testcase.T) {
+			resp := serveChild(t, http.MethodPut, http.Header{`If-Match`: {`"child-v1"`}})
+			require.NotEqual(t, http.StatusPreconditionFailed, resp.Code)
+			require.True(t, served(t))
+		})
+
+		s.Then(`If-Match is not required by the outer resource`, func(t *testcase.T) {
+			serveChild(t, http.MethodPut, nil)
+			require.True(t, served(t))
+		})
+	})
+}
+
+func TestHandler_conditionalGET(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Let(`resource`, func(t *testcase.T) interface{} { return ValidatedDocument{ETag: `v2`, Modified: modified} })
+	s.Let(`shown`, func(t *testcase.T) interface{} { return new(bool) })
+	var shown = func(t *testcase.T) bool { return *t.I(`shown`).(*bool) }
+
+	var serve = func(t *testcase.T, method string, header http.Header) *httptest.ResponseRecorder {
+		h := gorest.NewHandler(StubController{
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				return gorest.WithResource(ctx, t.I(`resource`)), true, nil
+			},
+			ShowFunc: func(w http.ResponseWriter, r *http.Request) {
+				*t.I(`shown`).(*bool) = true
+				_, _ = w.Write([]byte(`document`))
+			},
+		})
+		r := httptest.NewRequest(method, `/42`, nil)
+		for k, vs := range header {
+			r.Header[k] = vs
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	s.Test(`Show responses have the validators of the resource`, func(t *testcase.T) {
+		resp := serve(t, http.MethodGet, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `"v2"`, resp.Header().Get(`ETag`))
+		require.Equal(t, `Tue, 02 Jan 2024 03:04:05 GMT`, resp.Header().Get(`Last-Modified`))
+	})
+
+	for name, header := range map[string]http.Header{
+		`matching If-None-Match`:           {`If-None-Match`: {`"v1", W/"v2"`}},
+		`wildcard If-None-Match`:           {`If-None-Match`: {`*`}},
+		`If-Modified-Since at the change`:  {`If-Modified-Since`: {`Tue, 02 Jan 2024 03:04:05 GMT`}},
+		`If-Modified-Since after a change`: {`If-Modified-Since`: {`Wed, 03 Jan 2024 00:00:00 GMT`}},
+	} {
+		header := header
+
+		s.Test(name+` is answered with 304 without Show`, func(t *testcase.T) {
+			for _, method := range []string{http.MethodGet, http.MethodHead} {
+				resp := serve(t, method, header)
+				require.Equal(t, http.StatusNotModified, resp.Code)
+				require.Empty(t, resp.Body.String())
+				require.Equal(t, `"v2"`, resp.Header().Get(`ETag`))
+			}
+			require.False(t, shown(t))
+		})
+	}
+
+	for name, header := range map[string]http.Header{
+		`stale If-None-Match`:                          {`If-None-Match`: {`"v1"`}},
+		`If-Modified-Since before the change`:          {`If-Modified-Since`: {`Mon, 01 Jan 2024 00:00:00 GMT`}},
+		`If-Modified-Since overruled by If-None-Match`: {`If-None-Match`: {`"v1"`}, `If-Modified-Since`: {`Wed, 03 Jan 2024 00:00:00 GMT`}},
+		`malformed If-Modified-Since`:                  {`If-Modified-Since`: {`yesterday`}},
+	} {
+		header := header
+
+		s.Test(name+` is served by Show`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, header)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Equal(t, `document`, resp.Body.String())
+		})
+	}
+
+	s.Test(`a nested Show is not answered with the validators of the outer resource`, func(t *testcase.T) {
+		parent := gorest.NewHandler(StubController{
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				return gorest.WithResource(ctx, t.I(`resource`)), true, nil
+			},
+		})
+		child := gorest.NewHandler(gorest.AsShowController(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*t.I(`shown`).(*bool) = true
+			_, _ = w.Write([]byte(`child`))
+		})))
+		gorest.Mount(parent, `/children`, child)
+
+		r := httptest.NewRequest(http.MethodGet, `/1/children/7`, nil)
+		r.Header.Set(`If-None-Match`, `"v2"`)
+		r.Header.Set(`If-Modified-Since`, `Wed, 03 Jan 2024 00:00:00 GMT`)
+		w := httptest.NewRecorder()
+		parent.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `child`, w.Body.String())
+		require.Empty(t, w.Header().Get(`Last-Modified`))
+		require.True(t, shown(t))
+	})
+}
+
+func TestHandler_ListETags(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`body`, func(t *testcase.T) interface{} { return `[1,2,3]` })
+	s.Let(`enabled`, func(t *testcase.T) interface{} { return true })
+	var serve = func(t *testcase.T, header http.Header) *httptest.ResponseRecorder {
+		h := gorest.NewHandler(StubController{ListFunc: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(t.I(`body`).(string)))
+		}})
+		h.ListETags = t.I(`enabled`).(bool)
+		r := httptest.NewRequest(http.MethodGet, `/`, nil)
+		for k, vs := range header {
+			r.Header[k] = vs
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	s.Test(`List responses have a weak ETag computed from their body`, func(t *testcase.T) {
+		resp := serve(t, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `[1,2,3]`, resp.Body.String())
+		require.Regexp(t, `^W/"[0-9a-f]{32}"$`, resp.Header().Get(`ETag`))
+		require.Equal(t, resp.Header().Get(`ETag`), serve(t, nil).Header().Get(`ETag`))
+	})
+
+	s.Test(`matching If-None-Match is answered with 304`, func(t *testcase.T) {
+		etag := serve(t, nil).Header().Get(`ETag`)
+		resp := serve(t, http.Header{`If-None-Match`: {etag}})
+		require.Equal(t, http.StatusNotModified, resp.Code)
+		require.Empty(t, resp.Body.String())
+	})
+
+	s.Test(`changed body is served`, func(t *testcase.T) {
+		etag := serve(t, nil).Header().Get(`ETag`)
+		t.Let(`body`, `[1,2]`)
+		resp := serve(t, http.Header{`If-None-Match`: {etag}})
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `[1,2]`, resp.Body.String())
+	})
+
+	s.When(`ListETags is not enabled`, func(s *testcase.Spec) {
+		s.Let(`enabled`, func(t *testcase.T) interface{} { return false })
+
+		s.Then(`List responses have no ETag`, func(t *testcase.T) {
+			require.Empty(t, serve(t, nil).Header().Get(`ETag`))
+		})
+	})
+}
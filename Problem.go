@@ -0,0 +1,193 @@
+package gorest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemMediaType is the media type of the RFC 9457 problem details documents.
+const ProblemMediaType = `application/problem+json`
+
+// Problem is an RFC 9457 problem details document.
+type Problem struct {
+	// Type is a URI reference that identifies the problem type.
+	// When it is empty, "about:blank" is used.
+	Type string
+	// Title is a short summary of the problem type.
+	// When it is empty, the status text of Status is used.
+	Title string
+	// Status is the http status code of the response.
+	Status int
+	// Detail is an explanation specific to this occurrence of the problem.
+	Detail string
+	// Instance is a URI reference that identifies this occurrence of the problem.
+	// When it is empty, the request URI is used.
+	Instance string
+	// Extensions are additional members of the problem document.
+	Extensions map[string]interface{}
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]interface{}, len(p.Extensions)+5)
+	for name, value := range p.Extensions {
+		doc[name] = value
+	}
+	doc[`type`] = p.Type
+	doc[`title`] = p.Title
+	doc[`status`] = p.Status
+	if p.Detail != `` {
+		doc[`detail`] = p.Detail
+	}
+	if p.Instance != `` {
+		doc[`instance`] = p.Instance
+	}
+	return json.Marshal(doc)
+}
+
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*p = Problem{}
+	for name, raw := range doc {
+		var err error
+		switch name {
+		case `type`:
+			err = json.Unmarshal(raw, &p.Type)
+		case `title`:
+			err = json.Unmarshal(raw, &p.Title)
+		case `status`:
+			err = json.Unmarshal(raw, &p.Status)
+		case `detail`:
+			err = json.Unmarshal(raw, &p.Detail)
+		case `instance`:
+			err = json.Unmarshal(raw, &p.Instance)
+		default:
+			var value interface{}
+			err = json.Unmarshal(raw, &value)
+			if p.Extensions == nil {
+				p.Extensions = make(map[string]interface{})
+			}
+			p.Extensions[name] = value
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProblemDetails configures a Handler to reply its errors as problem details documents.
+type ProblemDetails struct {
+	// Extend is called with every problem before it is written,
+	// so custom members like a trace id can be added to it.
+	Extend func(r *http.Request, p *Problem)
+}
+
+type ctxKeyProblemDetails struct{}
+
+func problemDetailsFromContext(ctx context.Context) (*ProblemDetails, bool) {
+	pd, ok := ctx.Value(ctxKeyProblemDetails{}).(*ProblemDetails)
+	return pd, ok
+}
+
+// WriteProblem replies to the request with the problem details document.
+// The missing members of the problem are filled with their defaults,
+// and the Extend hook of the Handler's ProblemDetails is applied when there is one.
+//
+// When the requester prefers text/plain over JSON, the problem is written as plain text.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	pd, _ := problemDetailsFromContext(r.Context())
+	pd.write(w, r, p)
+}
+
+func (pd *ProblemDetails) write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Type == `` {
+		p.Type = `about:blank`
+	}
+	if p.Title == `` {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == `` {
+		p.Instance = requestURI(r)
+	}
+	if pd != nil && pd.Extend != nil {
+		pd.Extend(r, &p)
+	}
+
+	if !acceptsProblem(r.Header.Get(`Accept`)) {
+		msg := p.Title
+		if p.Detail != `` {
+			msg = p.Detail
+		}
+		http.Error(w, msg, p.Status)
+		return
+	}
+
+	bs, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, p.Title, p.Status)
+		return
+	}
+	w.Header().Del(`Content-Length`)
+	w.Header().Set(`Content-Type`, ProblemMediaType)
+	w.Header().Set(`X-Content-Type-Options`, `nosniff`)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(append(bs, '\n'))
+}
+
+// acceptsProblem reports whether the requester prefers a problem details document over plain text.
+// When the requester accepts neither, the problem details document is used.
+func acceptsProblem(accept string) bool {
+	for _, mr := range parseAccept(accept) {
+		if mr.q == 0 {
+			continue
+		}
+		if mr.match(ProblemMediaType) || mr.match(`application/json`) {
+			return true
+		}
+		if mr.match(`text/plain`) {
+			return false
+		}
+	}
+	return true
+}
+
+func requestURI(r *http.Request) string {
+	if r.RequestURI != `` {
+		return r.RequestURI
+	}
+	return r.URL.RequestURI()
+}
+
+// httpError replies to the request with an error response that gorest generates.
+// It is a problem details document when the Handler serving the request has ProblemDetails,
+// otherwise it is written as plain text.
+// An empty detail means that the status text describes the error.
+func httpError(w http.ResponseWriter, r *http.Request, code int, detail string) {
+	pd, ok := problemDetailsFromContext(r.Context())
+	if !ok {
+		switch {
+		case detail != ``:
+			http.Error(w, detail, code)
+		case code == http.StatusNotFound:
+			http.NotFound(w, r)
+		default:
+			http.Error(w, http.StatusText(code), code)
+		}
+		return
+	}
+
+	p := Problem{Status: code, Detail: detail}
+	var verr *ValidationError
+	if errors.As(ErrorFromContext(r.Context()), &verr) {
+		p.Extensions = map[string]interface{}{`fields`: verr.Fields}
+	}
+	pd.write(w, r, p)
+}
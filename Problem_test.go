@@ -0,0 +1,162 @@
+package gorest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestHandler_ProblemDetails(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Let(`ContextWithResource error`, func(t *testcase.T) interface{} { return nil })
+	s.Let(`handler`, func(t *testcase.T) interface{} {
+		h := gorest.NewHandler(StubController{
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				err, _ := t.I(`ContextWithResource error`).(error)
+				return ctx, id == `42`, err
+			},
+		})
+		h.ProblemDetails = &gorest.ProblemDetails{}
+		return h
+	})
+	var handler = func(t *testcase.T) *gorest.Handler { return t.I(`handler`).(*gorest.Handler) }
+
+	s.Let(`Accept`, func(t *testcase.T) interface{} { return `` })
+	var serve = func(t *testcase.T, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set(`Accept`, t.I(`Accept`).(string))
+		handler(t).ServeHTTP(w, r)
+		return w
+	}
+	var problemOf = func(t *testcase.T, resp *httptest.ResponseRecorder) gorest.Problem {
+		require.Equal(t, gorest.ProblemMediaType, resp.Header().Get(`Content-Type`))
+		var p gorest.Problem
+		require.Nil(t, json.Unmarshal(resp.Body.Bytes(), &p))
+		return p
+	}
+
+	s.Test(`not found`, func(t *testcase.T) {
+		resp := serve(t, http.MethodGet, `/24?q=1`)
+		require.Equal(t, http.StatusNotFound, resp.Code)
+		require.Equal(t, gorest.Problem{
+			Type:     `about:blank`,
+			Title:    `Not Found`,
+			Status:   http.StatusNotFound,
+			Instance: `/24?q=1`,
+		}, problemOf(t, resp))
+	})
+
+	s.Test(`method not allowed`, func(t *testcase.T) {
+		resp := serve(t, http.MethodPost, `/42`)
+		require.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+		require.NotEmpty(t, resp.Header().Get(`Allow`))
+		require.Equal(t, http.StatusMethodNotAllowed, problemOf(t, resp).Status)
+	})
+
+	s.Test(`internal server error`, func(t *testcase.T) {
+		t.Let(`ContextWithResource error`, errors.New(`boom`))
+		resp := serve(t, http.MethodGet, `/42`)
+		require.Equal(t, http.StatusInternalServerError, resp.Code)
+		p := problemOf(t, resp)
+		require.Equal(t, `Internal Server Error`, p.Title)
+		require.Empty(t, p.Detail, `internal error details should not leak`)
+	})
+
+	s.Test(`mapped error`, func(t *testcase.T) {
+		t.Let(`ContextWithResource error`, &gorest.ValidationError{Fields: map[string]string{`id`: `must be even`}})
+		resp := serve(t, http.MethodGet, `/42`)
+		require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		p := problemOf(t, resp)
+		require.Equal(t, `gorest: validation failed: id: must be even`, p.Detail)
+		require.Equal(t, map[string]interface{}{`id`: `must be even`}, p.Extensions[`fields`])
+	})
+
+	s.Test(`custom members are added with the Extend hook`, func(t *testcase.T) {
+		handler(t).ProblemDetails.Extend = func(r *http.Request, p *gorest.Problem) {
+			p.Type = `https://example.com/problems/` + r.Method
+			p.Extensions = map[string]interface{}{`traceId`: `trace-1`}
+		}
+		p := problemOf(t, serve(t, http.MethodGet, `/24`))
+		require.Equal(t, `https://example.com/problems/GET`, p.Type)
+		require.Equal(t, `trace-1`, p.Extensions[`traceId`])
+	})
+
+	s.Test(`custom error handlers are kept`, func(t *testcase.T) {
+		handler(t).NotFound = NewTestControllerMockHandler(nil, http.StatusTeapot, `custom-not-found`)
+		resp := serve(t, http.MethodGet, `/24`)
+		require.Equal(t, http.StatusTeapot, resp.Code)
+		require.Contains(t, resp.Body.String(), `custom-not-found`)
+	})
+
+	s.Test(`nested handlers inherit the setting`, func(t *testcase.T) {
+		gorest.Mount(handler(t), `/pets/`, gorest.NewHandler(gorest.AsListController(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+		resp := serve(t, http.MethodDelete, `/42/pets/`)
+		require.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+		require.Equal(t, `/42/pets/`, problemOf(t, resp).Instance)
+	})
+
+	s.When(`the requester prefers plain text`, func(s *testcase.Spec) {
+		s.Let(`Accept`, func(t *testcase.T) interface{} { return `text/plain, application/json;q=0.5` })
+
+		s.Then(`the error is written as plain text`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/24`)
+			require.Equal(t, http.StatusNotFound, resp.Code)
+			require.Contains(t, resp.Header().Get(`Content-Type`), `text/plain`)
+			require.Contains(t, resp.Body.String(), `Not Found`)
+		})
+	})
+
+	s.When(`the requester accepts JSON`, func(s *testcase.Spec) {
+		s.Let(`Accept`, func(t *testcase.T) interface{} { return `application/json, text/plain;q=0.5` })
+
+		s.Then(`the error is written as problem details`, func(t *testcase.T) {
+			require.Equal(t, http.StatusNotFound, problemOf(t, serve(t, http.MethodGet, `/24`)).Status)
+		})
+	})
+}
+
+func TestWriteProblem(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`the missing members are filled`, func(t *testcase.T) {
+		w := httptest.NewRecorder()
+		gorest.WriteProblem(w, httptest.NewRequest(http.MethodPut, `/users/1`, nil), gorest.Problem{
+			Status:     http.StatusConflict,
+			Detail:     `the user was modified`,
+			Extensions: map[string]interface{}{`version`: `2`},
+		})
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.Equal(t, gorest.ProblemMediaType, w.Header().Get(`Content-Type`))
+		require.JSONEq(t, `{
+			"type": "about:blank",
+			"title": "Conflict",
+			"status": 409,
+			"detail": "the user was modified",
+			"instance": "/users/1",
+			"version": "2"
+		}`, w.Body.String())
+	})
+
+	s.Test(`the Extend hook of the Handler is applied`, func(t *testcase.T) {
+		h := gorest.NewHandler(gorest.AsShowController(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gorest.WriteProblem(w, r, gorest.Problem{Status: http.StatusGone})
+		})))
+		h.ProblemDetails = &gorest.ProblemDetails{Extend: func(r *http.Request, p *gorest.Problem) {
+			p.Title = `custom`
+		}}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/1`, nil))
+		require.Equal(t, http.StatusGone, w.Code)
+		require.Contains(t, w.Body.String(), `"title":"custom"`)
+	})
+}
@@ -0,0 +1,124 @@
+package gorest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Resource starts the declaration of a resource tree with a top level collection.
+// The controller can be anything that NewHandler accepts, or an already configured *Handler.
+//
+// example:
+//	users := gorest.Resource(`users`, UsersController{})
+//	users.Sub(`organizations`, OrganizationsController{}).Sub(`permissions`, PermissionsController{})
+//	users.Sub(`contacts`, ContactsController{})
+//	handler, err := users.Build()
+//
+// this will serve the following paths:
+//	/users/{users-id}/organizations/{organizations-id}/permissions/{permissions-id}
+//	/users/{users-id}/contacts/{contacts-id}
+func Resource(name string, ctrl interface{}) *ResourceNode {
+	return &ResourceNode{name: name, ctrl: ctrl}
+}
+
+// ResourceNode represents a collection in a resource tree.
+type ResourceNode struct {
+	name   string
+	ctrl   interface{}
+	parent *ResourceNode
+	subs   []*ResourceNode
+}
+
+// Sub declares a sub collection under the resources of the current collection,
+// and returns the sub collection node, so further nesting can be chained.
+func (n *ResourceNode) Sub(name string, ctrl interface{}) *ResourceNode {
+	sub := &ResourceNode{name: name, ctrl: ctrl, parent: n}
+	n.subs = append(n.subs, sub)
+	return sub
+}
+
+// Root returns the top level collection of the resource tree.
+func (n *ResourceNode) Root() *ResourceNode {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// Build creates the http.Handler for the whole resource tree, regardless which node it is called on.
+// Each collection's ContextHandler is applied in order from the top level collection to the requested one.
+// The sub collections are mounted on the already configured *Handler controllers,
+// so building the tree again fails, when such a Handler has sub collections.
+func (n *ResourceNode) Build() (http.Handler, error) {
+	root := n.Root()
+	h, err := root.handler()
+	if err != nil {
+		return nil, err
+	}
+	tree := &resourceTree{ServeMux: http.NewServeMux()}
+	Mount(tree, root.pattern(), h)
+	return tree, nil
+}
+
+// resourceTree is the http.Handler of a built resource tree.
+type resourceTree struct {
+	*http.ServeMux
+	root *mountedHandler
+}
+
+func (t *resourceTree) Handle(pattern string, handler http.Handler) {
+	if mh, ok := handler.(*mountedHandler); ok {
+		t.root = mh
+	}
+	t.ServeMux.Handle(pattern, handler)
+}
+
+func (t *resourceTree) walkRoutes(base routeBase, fn func(Route) error) error {
+	return t.root.walkRoutes(base, fn)
+}
+
+func (n *ResourceNode) handler() (*Handler, error) {
+	if n.pattern() == `/` {
+		return nil, fmt.Errorf(`gorest: resource name is missing at %q`, n.path())
+	}
+
+	var h *Handler
+	if ch, ok := n.ctrl.(*Handler); ok {
+		h = ch
+	} else {
+		h = NewHandler(n.ctrl)
+	}
+
+	names := make(map[string]struct{})
+	for _, sub := range n.subs {
+		pattern := sub.pattern()
+		if _, ok := names[pattern]; ok {
+			return nil, fmt.Errorf(`gorest: duplicate resource name %q under %q`, sub.name, n.path())
+		}
+		names[pattern] = struct{}{}
+
+		if h.handlers.isRegistered(pattern) || h.handlers.isRegistered(pattern+`/`) {
+			return nil, fmt.Errorf(`gorest: %q is already registered on the handler of %q`, pattern, n.path())
+		}
+
+		sh, err := sub.handler()
+		if err != nil {
+			return nil, err
+		}
+		Mount(h, pattern, sh)
+	}
+	return h, nil
+}
+
+func (n *ResourceNode) pattern() string {
+	return `/` + strings.Trim(n.name, `/`)
+}
+
+// path returns the human readable path of the collection for error messages.
+func (n *ResourceNode) path() string {
+	if n.parent == nil {
+		return n.pattern()
+	}
+	return n.parent.path() + `/{id}` + n.pattern()
+}
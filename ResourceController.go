@@ -0,0 +1,181 @@
+package gorest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Repository is the storage dependency of a ResourceController.
+type Repository[T any, ID any] interface {
+	// FindAll returns every entity of the collection.
+	FindAll(ctx context.Context) ([]T, error)
+	// Create stores a new entity, and it is expected to update the entity with the generated values like the id.
+	Create(ctx context.Context, entity *T) error
+	// FindByID looks up an entity by its id, and reports with found whether it exists.
+	FindByID(ctx context.Context, id ID) (entity T, found bool, err error)
+	// Update stores the new state of the entity that is identified by id.
+	Update(ctx context.Context, id ID, entity *T) error
+	// DeleteByID removes the entity that is identified by id.
+	DeleteByID(ctx context.Context, id ID) error
+}
+
+// ResourceController is a Controller implementation for collections that map directly to a Repository.
+// The request and response bodies are the entities, encoded with the codecs of the Handler.
+//
+// The entity is loaded in ContextWithResource, so Show, Update and Delete can rely on its existence,
+// and sub collections can access it with FromContext.
+// The entity is also stored with WithResource, so when T is Versioned, the Handler checks the If-Match preconditions.
+//
+// The errors of the Repository are replied with WriteError,
+// so a Repository can use the gorest errors like ErrConflict or ValidationError to reject a request.
+type ResourceController[T any, ID any] struct {
+	Repository Repository[T, ID]
+	// ParseID parses the resource id path parameter.
+	// When the id is not parsable, the resource is reported as not found.
+	// It can be left nil when the underlying type of ID is string, otherwise the resource requests fail with 500.
+	ParseID func(string) (ID, error)
+}
+
+type resourceControllerContextKey[T any, ID any] struct{}
+
+type resourceControllerContextValue[T any, ID any] struct {
+	id     ID
+	entity T
+}
+
+// FromContext returns the entity that was loaded into the context by ContextWithResource.
+func (ctrl ResourceController[T, ID]) FromContext(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(resourceControllerContextKey[T, ID]{}).(resourceControllerContextValue[T, ID])
+	return v.entity, ok
+}
+
+// IDFromContext returns the parsed resource id that was stored into the context by ContextWithResource.
+func (ctrl ResourceController[T, ID]) IDFromContext(ctx context.Context) (ID, bool) {
+	v, ok := ctx.Value(resourceControllerContextKey[T, ID]{}).(resourceControllerContextValue[T, ID])
+	return v.id, ok
+}
+
+func (ctrl ResourceController[T, ID]) List(w http.ResponseWriter, r *http.Request) {
+	entities, err := ctrl.Repository.FindAll(r.Context())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if entities == nil {
+		entities = []T{}
+	}
+	ctrl.encode(w, r, http.StatusOK, entities)
+}
+
+func (ctrl ResourceController[T, ID]) Create(w http.ResponseWriter, r *http.Request) {
+	var entity T
+	if err := Decode(r, &entity); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := ctrl.Repository.Create(r.Context(), &entity); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	ctrl.encode(w, r, http.StatusCreated, entity)
+}
+
+func (ctrl ResourceController[T, ID]) ContextWithResource(ctx context.Context, resourceID string) (context.Context, bool, error) {
+	parse, err := ctrl.idParser()
+	if err != nil {
+		return ctx, false, err
+	}
+	id, err := parse(resourceID)
+	if err != nil {
+		return ctx, false, nil
+	}
+	entity, found, err := ctrl.Repository.FindByID(ctx, id)
+	if err != nil || !found {
+		return ctx, false, err
+	}
+	ctx = WithResource(ctx, entity)
+	return context.WithValue(ctx, resourceControllerContextKey[T, ID]{}, resourceControllerContextValue[T, ID]{
+		id:     id,
+		entity: entity,
+	}), true, nil
+}
+
+// ValidResourceID reports whether the resource id can be parsed with ParseID.
+func (ctrl ResourceController[T, ID]) ValidResourceID(resourceID string) bool {
+	parse, err := ctrl.idParser()
+	if err != nil {
+		return false
+	}
+	_, err = parse(resourceID)
+	return err == nil
+}
+
+func (ctrl ResourceController[T, ID]) Show(w http.ResponseWriter, r *http.Request) {
+	entity, _ := ctrl.FromContext(r.Context())
+	ctrl.encode(w, r, http.StatusOK, entity)
+}
+
+// Update decodes the request body over the loaded entity,
+// so fields missing from the request body keep their current value.
+// When the request has a field mask, only the fields of the mask are changed.
+func (ctrl ResourceController[T, ID]) Update(w http.ResponseWriter, r *http.Request) {
+	v, _ := r.Context().Value(resourceControllerContextKey[T, ID]{}).(resourceControllerContextValue[T, ID])
+	entity := v.entity
+	if err := ctrl.decodeUpdate(r, &entity); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if err := ctrl.Repository.Update(r.Context(), v.id, &entity); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	ctrl.encode(w, r, http.StatusOK, entity)
+}
+
+func (ctrl ResourceController[T, ID]) Delete(w http.ResponseWriter, r *http.Request) {
+	id, _ := ctrl.IDFromContext(r.Context())
+	if err := ctrl.Repository.DeleteByID(r.Context(), id); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ctrl ResourceController[T, ID]) decodeUpdate(r *http.Request, entity *T) error {
+	mask, ok := FieldMaskFromContext(r.Context())
+	if !ok {
+		return Decode(r, entity)
+	}
+	var patch T
+	if err := Decode(r, &patch); err != nil {
+		return err
+	}
+	return mask.Copy(entity, &patch)
+}
+
+// idParser returns the parser of the resource ids.
+// A missing ParseID for non string ids is a configuration error, and not a not found resource.
+func (ctrl ResourceController[T, ID]) idParser() (func(string) (ID, error), error) {
+	if ctrl.ParseID != nil {
+		return ctrl.ParseID, nil
+	}
+	if _, ok := interface{}(``).(ID); ok {
+		return func(resourceID string) (ID, error) { return interface{}(resourceID).(ID), nil }, nil
+	}
+	var zero ID
+	if reflect.TypeOf(&zero).Elem().Kind() != reflect.String {
+		return nil, fmt.Errorf(`gorest: ResourceController.ParseID is required for %T ids, which are not strings`, zero)
+	}
+	return func(resourceID string) (ID, error) {
+		var id ID
+		reflect.ValueOf(&id).Elem().SetString(resourceID)
+		return id, nil
+	}, nil
+}
+
+// encode replies with the entity, Encode already replies its own errors, so they are not handled here.
+func (ctrl ResourceController[T, ID]) encode(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	_ = Encode(w, r, code, v)
+}
@@ -0,0 +1,247 @@
+package gorest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+var _ gorest.Controller = gorest.ResourceController[User, int]{}
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type UserRepository struct {
+	Users  map[int]User
+	NextID int
+	Err    error
+}
+
+func (repo *UserRepository) FindAll(ctx context.Context) ([]User, error) {
+	var users []User
+	for id := 1; id <= repo.NextID; id++ {
+		if u, ok := repo.Users[id]; ok {
+			users = append(users, u)
+		}
+	}
+	return users, repo.Err
+}
+
+func (repo *UserRepository) Create(ctx context.Context, u *User) error {
+	if repo.Err != nil {
+		return repo.Err
+	}
+	repo.NextID++
+	u.ID = repo.NextID
+	repo.Users[u.ID] = *u
+	return nil
+}
+
+func (repo *UserRepository) FindByID(ctx context.Context, id int) (User, bool, error) {
+	u, ok := repo.Users[id]
+	return u, ok, repo.Err
+}
+
+func (repo *UserRepository) Update(ctx context.Context, id int, u *User) error {
+	if repo.Err != nil {
+		return repo.Err
+	}
+	u.ID = id
+	repo.Users[id] = *u
+	return nil
+}
+
+func (repo *UserRepository) DeleteByID(ctx context.Context, id int) error {
+	if repo.Err != nil {
+		return repo.Err
+	}
+	delete(repo.Users, id)
+	return nil
+}
+
+type AccountID string
+
+type Account struct {
+	ID   AccountID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type AccountRepository struct {
+	Accounts map[AccountID]Account
+}
+
+func (repo *AccountRepository) FindAll(ctx context.Context) ([]Account, error) {
+	var accounts []Account
+	for _, a := range repo.Accounts {
+		accounts = append(accounts, a)
+	}
+	return accounts, nil
+}
+
+func (repo *AccountRepository) Create(ctx context.Context, a *Account) error {
+	repo.Accounts[a.ID] = *a
+	return nil
+}
+
+func (repo *AccountRepository) FindByID(ctx context.Context, id AccountID) (Account, bool, error) {
+	a, ok := repo.Accounts[id]
+	return a, ok, nil
+}
+
+func (repo *AccountRepository) Update(ctx context.Context, id AccountID, a *Account) error {
+	a.ID = id
+	repo.Accounts[id] = *a
+	return nil
+}
+
+func (repo *AccountRepository) DeleteByID(ctx context.Context, id AccountID) error {
+	delete(repo.Accounts, id)
+	return nil
+}
+
+func TestResourceController(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	var repository = func(t *testcase.T) *UserRepository { return t.I(`repository`).(*UserRepository) }
+	s.Let(`repository`, func(t *testcase.T) interface{} {
+		return &UserRepository{Users: map[int]User{1: {ID: 1, Name: `Jane`, Age: 42}}, NextID: 1}
+	})
+	s.Let(`controller`, func(t *testcase.T) interface{} {
+		return gorest.ResourceController[User, int]{
+			Repository: repository(t),
+			ParseID:    strconv.Atoi,
+		}
+	})
+
+	var serve = func(t *testcase.T, method, path string, body io.Reader) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		gorest.NewHandler(t.I(`controller`)).ServeHTTP(w, httptest.NewRequest(method, path, body))
+		return w
+	}
+
+	s.Test(`List`, func(t *testcase.T) {
+		resp := serve(t, http.MethodGet, `/`, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `application/json`, resp.Header().Get(`Content-Type`))
+		var users []User
+		require.Nil(t, json.Unmarshal(resp.Body.Bytes(), &users))
+		require.Equal(t, []User{{ID: 1, Name: `Jane`, Age: 42}}, users)
+	})
+
+	s.Test(`Create`, func(t *testcase.T) {
+		resp := serve(t, http.MethodPost, `/`, strings.NewReader(`{"name":"John","age":24}`))
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var u User
+		require.Nil(t, json.Unmarshal(resp.Body.Bytes(), &u))
+		require.Equal(t, User{ID: 2, Name: `John`, Age: 24}, u)
+		require.Equal(t, u, repository(t).Users[2])
+	})
+
+	s.Test(`Create with invalid body`, func(t *testcase.T) {
+		resp := serve(t, http.MethodPost, `/`, strings.NewReader(`{`))
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	s.Test(`Create with unsupported media type`, func(t *testcase.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, `/`, strings.NewReader(`name: John`))
+		r.Header.Set(`Content-Type`, `application/yaml`)
+		gorest.NewHandler(t.I(`controller`)).ServeHTTP(w, r)
+		require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	s.Test(`Show`, func(t *testcase.T) {
+		resp := serve(t, http.MethodGet, `/1`, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		var u User
+		require.Nil(t, json.Unmarshal(resp.Body.Bytes(), &u))
+		require.Equal(t, User{ID: 1, Name: `Jane`, Age: 42}, u)
+	})
+
+	s.Test(`Show unknown resource`, func(t *testcase.T) {
+		require.Equal(t, http.StatusNotFound, serve(t, http.MethodGet, `/2`, nil).Code)
+	})
+
+	s.Test(`Show with unparsable id`, func(t *testcase.T) {
+		require.Equal(t, http.StatusNotFound, serve(t, http.MethodGet, `/abc`, nil).Code)
+	})
+
+	s.Test(`Update keeps the fields that are not present in the request`, func(t *testcase.T) {
+		resp := serve(t, http.MethodPatch, `/1`, strings.NewReader(`{"age":43}`))
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, User{ID: 1, Name: `Jane`, Age: 43}, repository(t).Users[1])
+	})
+
+	s.Test(`Delete`, func(t *testcase.T) {
+		resp := serve(t, http.MethodDelete, `/1`, nil)
+		require.Equal(t, http.StatusNoContent, resp.Code)
+		require.NotContains(t, repository(t).Users, 1)
+	})
+
+	s.Test(`repository error`, func(t *testcase.T) {
+		repository(t).Err = errors.New(`boom`)
+		require.Equal(t, http.StatusInternalServerError, serve(t, http.MethodGet, `/`, nil).Code)
+		require.Equal(t, http.StatusInternalServerError, serve(t, http.MethodGet, `/1`, nil).Code)
+	})
+
+	s.Test(`repository error with a gorest error`, func(t *testcase.T) {
+		repository(t).Err = fmt.Errorf(`duplicate name: %w`, gorest.ErrConflict)
+		require.Equal(t, http.StatusConflict, serve(t, http.MethodPost, `/`, strings.NewReader(`{"name":"Jane"}`)).Code)
+		repository(t).Err = &gorest.ValidationError{Fields: map[string]string{`age`: `must be positive`}}
+		resp := serve(t, http.MethodGet, `/1`, nil)
+		require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		require.Contains(t, resp.Body.String(), `age: must be positive`)
+	})
+
+	s.Test(`FromContext`, func(t *testcase.T) {
+		ctrl := t.I(`controller`).(gorest.ResourceController[User, int])
+		ctx, found, err := ctrl.ContextWithResource(context.Background(), `1`)
+		require.Nil(t, err)
+		require.True(t, found)
+		u, ok := ctrl.FromContext(ctx)
+		require.True(t, ok)
+		require.Equal(t, `Jane`, u.Name)
+		id, ok := ctrl.IDFromContext(ctx)
+		require.True(t, ok)
+		require.Equal(t, 1, id)
+
+		_, ok = ctrl.FromContext(context.Background())
+		require.False(t, ok)
+	})
+
+	s.Test(`named string ids without ParseID`, func(t *testcase.T) {
+		t.Let(`controller`, gorest.ResourceController[Account, AccountID]{
+			Repository: &AccountRepository{Accounts: map[AccountID]Account{`42`: {ID: `42`, Name: `Jane`}}},
+		})
+
+		resp := serve(t, http.MethodGet, `/42`, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.JSONEq(t, `{"id":"42","name":"Jane"}`, resp.Body.String())
+		require.Equal(t, http.StatusNotFound, serve(t, http.MethodGet, `/24`, nil).Code)
+	})
+
+	s.Test(`non string ids without ParseID`, func(t *testcase.T) {
+		ctrl := gorest.ResourceController[User, int]{Repository: repository(t)}
+		_, found, err := ctrl.ContextWithResource(context.Background(), `1`)
+		require.Error(t, err)
+		require.False(t, found)
+
+		t.Let(`controller`, ctrl)
+		require.Equal(t, http.StatusInternalServerError, serve(t, http.MethodGet, `/1`, nil).Code)
+	})
+}
@@ -0,0 +1,153 @@
+package gorest_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestResource(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	type ctxKeyUser struct{}
+	type ctxKeyOrg struct{}
+
+	var users = func(t *testcase.T) *gorest.ResourceNode { return t.I(`users`).(*gorest.ResourceNode) }
+	s.Let(`users`, func(t *testcase.T) interface{} {
+		return gorest.Resource(`users`, StubController{
+			ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+				return context.WithValue(ctx, ctxKeyUser{}, id), id != `404`, nil
+			},
+			ShowFunc: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintf(w, `user:%s`, r.Context().Value(ctxKeyUser{}))
+			},
+		})
+	})
+
+	var serve = func(t *testcase.T, method, path string) *httptest.ResponseRecorder {
+		h, err := users(t).Build()
+		require.Nil(t, err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	s.When(`the tree has nested collections`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			orgs := users(t).Sub(`organizations`, StubController{
+				ContextWithResourceFunc: func(ctx context.Context, id string) (context.Context, bool, error) {
+					return context.WithValue(ctx, ctxKeyOrg{}, id), true, nil
+				},
+			})
+			orgs.Sub(`/permissions/`, StubController{
+				ShowFunc: func(w http.ResponseWriter, r *http.Request) {
+					_, _ = fmt.Fprintf(w, `user:%s|org:%s`, r.Context().Value(ctxKeyUser{}), r.Context().Value(ctxKeyOrg{}))
+				},
+			})
+			users(t).Sub(`contacts`, StubController{
+				ListFunc: func(w http.ResponseWriter, r *http.Request) {
+					_, _ = fmt.Fprintf(w, `contacts-of:%s`, r.Context().Value(ctxKeyUser{}))
+				},
+			})
+		})
+
+		s.Then(`the top level collection is served`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/users/42`)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Equal(t, `user:42`, resp.Body.String())
+		})
+
+		s.Then(`each level's context handler is applied in order`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/users/42/organizations/24/permissions/1`)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Equal(t, `user:42|org:24`, resp.Body.String())
+		})
+
+		s.Then(`sibling collections are served`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/users/42/contacts`)
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Equal(t, `contacts-of:42`, resp.Body.String())
+		})
+
+		s.Then(`a not found parent resource hides the sub collections`, func(t *testcase.T) {
+			resp := serve(t, http.MethodGet, `/users/404/organizations/24/permissions/1`)
+			require.Equal(t, http.StatusNotFound, resp.Code)
+		})
+
+		s.Then(`the tree can be built from any node`, func(t *testcase.T) {
+			h, err := users(t).Sub(`other`, StubController{}).Build()
+			require.Nil(t, err)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/users/42`, nil))
+			require.Equal(t, `user:42`, w.Body.String())
+		})
+	})
+
+	s.When(`a sub collection name is declared twice under the same collection`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			users(t).Sub(`organizations`, StubController{})
+			users(t).Sub(`/organizations`, StubController{})
+		})
+
+		s.Then(`it will report an error`, func(t *testcase.T) {
+			_, err := users(t).Build()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), `duplicate resource name`)
+		})
+	})
+
+	s.When(`a sub collection name is empty`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { users(t).Sub(``, StubController{}) })
+
+		s.Then(`it will report an error`, func(t *testcase.T) {
+			_, err := users(t).Build()
+			require.Error(t, err)
+		})
+	})
+
+	s.When(`an already configured handler is used`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			h := gorest.NewHandler(nil)
+			h.NotFound = NewTestControllerMockHandler(nil, http.StatusTeapot, `teapot`)
+			users(t).Sub(`teapots`, h)
+		})
+
+		s.Then(`the handler is used as is`, func(t *testcase.T) {
+			require.Equal(t, http.StatusTeapot, serve(t, http.MethodGet, `/users/42/teapots`).Code)
+		})
+	})
+
+	s.When(`an already configured handler has a route with the name of a sub collection`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			h := gorest.NewHandler(StubController{})
+			h.Handle(`/teapots`, NewTestControllerMockHandler(nil, http.StatusTeapot, `teapot`))
+			users(t).Sub(`orgs`, h).Sub(`teapots`, StubController{})
+		})
+
+		s.Then(`it will report an error`, func(t *testcase.T) {
+			_, err := users(t).Build()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), `"/teapots" is already registered`)
+		})
+	})
+
+	s.When(`a tree with an already configured handler is built twice`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			users(t).Sub(`orgs`, gorest.NewHandler(StubController{})).Sub(`teapots`, StubController{})
+		})
+
+		s.Then(`the second build reports an error instead of panicking`, func(t *testcase.T) {
+			_, err := users(t).Build()
+			require.Nil(t, err)
+			_, err = users(t).Build()
+			require.Error(t, err)
+		})
+	})
+}
@@ -0,0 +1,252 @@
+package gorest
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Operation represents the kind of operation a route serves.
+// Operations are bit flags, so they can be combined to represent a set of operations: OpCreate|OpUpdate
+type Operation uint
+
+const (
+	// OpList -- GET /
+	OpList Operation = 1 << iota
+	// OpCreate -- POST /
+	OpCreate
+	// OpShow -- GET /{resourceID}
+	OpShow
+	// OpUpdate -- PUT|PATCH /{resourceID}
+	OpUpdate
+	// OpDelete -- DELETE /{resourceID}
+	OpDelete
+	// OpCustom represents the custom operations registered with Handler.Handle and Handler.HandleCustom.
+	OpCustom
+)
+
+var operationNames = []struct {
+	op   Operation
+	name string
+}{
+	{op: OpList, name: `List`},
+	{op: OpCreate, name: `Create`},
+	{op: OpShow, name: `Show`},
+	{op: OpUpdate, name: `Update`},
+	{op: OpDelete, name: `Delete`},
+	{op: OpCustom, name: `Custom`},
+}
+
+func (op Operation) String() string {
+	var names []string
+	for _, on := range operationNames {
+		if op&on.op != 0 {
+			names = append(names, on.name)
+		}
+	}
+	return strings.Join(names, `|`)
+}
+
+// Route describes an operation that a Handler tree serves.
+type Route struct {
+	// Method is the http method of the route.
+	// An empty Method means that the route accepts any http method, which is the case with Handler.Handle.
+	Method string
+	// Path is the path template of the route, where the resource ids are represented as {name} path parameters.
+	// example:
+	//	/{id}/organizations/{organizations-id}
+	Path string
+	// PathParams are the path parameter names in the order they appear in the Path.
+	PathParams []string
+	// Operation is the kind of the operation the route serves.
+	Operation Operation
+	// ControllerType is the type of the controller that serves the route.
+	// In case of a custom operation, it is the type of the registered http.Handler.
+	ControllerType reflect.Type
+
+	controller interface{}
+}
+
+// Routes returns every route the Handler serves, including the routes of the nested Handlers
+// that were registered with Handle or Mount.
+func (h *Handler) Routes() []Route {
+	var routes []Route
+	_ = h.walkRoutes(routeBase{idName: `id`}, func(r Route) error {
+		routes = append(routes, r)
+		return nil
+	})
+	return routes
+}
+
+// WalkRoutes calls fn for each route that the handler tree serves.
+// The walk goes recursively across the nested Handlers registered through Handle and Mount.
+// If fn returns an error, the walk stops and the error is returned.
+func WalkRoutes(handler http.Handler, fn func(Route) error) error {
+	return walkRoutes(handler, routeBase{idName: `id`}, fn)
+}
+
+type routeWalker interface {
+	walkRoutes(base routeBase, fn func(Route) error) error
+}
+
+// routeBase is the position of a handler in the route tree.
+type routeBase struct {
+	path   string
+	params []string
+	// idName is the path parameter name used for the resource id of the handler.
+	idName string
+}
+
+func (b routeBase) collectionPath() string {
+	if b.path == `` {
+		return `/`
+	}
+	return b.path
+}
+
+func (b routeBase) resource() routeBase {
+	params := make([]string, len(b.params), len(b.params)+1)
+	copy(params, b.params)
+	return routeBase{
+		path:   b.path + `/{` + b.idName + `}`,
+		params: append(params, b.idName),
+	}
+}
+
+func (b routeBase) mount(prefix string) routeBase {
+	prefix = strings.TrimSuffix(prefix, `/`)
+	return routeBase{
+		path:   b.path + prefix,
+		params: b.params,
+		idName: prefix[strings.LastIndex(prefix, `/`)+1:] + `-id`,
+	}
+}
+
+func walkRoutes(handler http.Handler, base routeBase, fn func(Route) error) error {
+	if rw, ok := handler.(routeWalker); ok {
+		return rw.walkRoutes(base, fn)
+	}
+	return nil
+}
+
+func (h *Handler) walkRoutes(base routeBase, fn func(Route) error) error {
+	ctrlType := reflect.TypeOf(h.controller)
+
+	for _, method := range h.operations.collection.Methods() {
+		if err := fn(Route{
+			Method:         method,
+			Path:           base.collectionPath(),
+			PathParams:     base.params,
+			Operation:      h.operations.collection.routes[method].kind,
+			ControllerType: ctrlType,
+			controller:     h.controller,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := h.walkCustomMethods(CollectionScope, base.path, base.params, fn); err != nil {
+		return err
+	}
+
+	for _, rh := range h.collectionHandlers.registered {
+		handler := rh.handler.(customOperation).handler
+		if err := fn(Route{
+			Path:           base.path + rh.pattern,
+			PathParams:     base.params,
+			Operation:      OpCustom,
+			ControllerType: reflect.TypeOf(handler),
+			controller:     handler,
+		}); err != nil {
+			return err
+		}
+	}
+
+	resource := base.resource()
+	for _, method := range h.operations.resource.Methods() {
+		if err := fn(Route{
+			Method:         method,
+			Path:           resource.path,
+			PathParams:     resource.params,
+			Operation:      h.operations.resource.routes[method].kind,
+			ControllerType: ctrlType,
+			controller:     h.controller,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := h.walkCustomMethods(ResourceScope, resource.path, resource.params, fn); err != nil {
+		return err
+	}
+
+	mounted := make(map[*mountedHandler]struct{})
+	for _, rh := range h.handlers.registered {
+		if mh, ok := rh.handler.(*mountedHandler); ok {
+			if _, ok := mounted[mh]; ok {
+				continue
+			}
+			mounted[mh] = struct{}{}
+
+			if err := mh.walkRoutes(resource, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		handler := rh.handler
+		if co, ok := handler.(customOperation); ok {
+			handler = co.handler
+		}
+		if err := fn(Route{
+			Path:           resource.path + rh.pattern,
+			PathParams:     resource.params,
+			Operation:      OpCustom,
+			ControllerType: reflect.TypeOf(handler),
+			controller:     handler,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkCustomMethods calls fn for the custom methods of the scope, where path is the path of the scope.
+func (h *Handler) walkCustomMethods(scope Scope, path string, params []string, fn func(Route) error) error {
+	var verbs []string
+	switch scope {
+	case CollectionScope:
+		verbs = h.collectionVerbs()
+	case ResourceScope:
+		for verb := range h.customMethods.resource {
+			verbs = append(verbs, verb)
+		}
+	}
+	sort.Strings(verbs)
+	if path == `` {
+		path = `/`
+	}
+
+	for _, verb := range verbs {
+		ops, _ := h.lookupCustomMethod(scope, verb)
+		for _, method := range ops.Methods() {
+			handler := ops.routes[method].handler
+			if err := fn(Route{
+				Method:         method,
+				Path:           path + `:` + verb,
+				PathParams:     params,
+				Operation:      OpCustom,
+				ControllerType: reflect.TypeOf(handler),
+				controller:     handler,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mountedHandler) walkRoutes(base routeBase, fn func(Route) error) error {
+	return walkRoutes(m.handler, base.mount(m.prefix), fn)
+}
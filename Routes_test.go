@@ -0,0 +1,128 @@
+package gorest_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func TestHandler_Routes(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	var subject = func(t *testcase.T) []gorest.Route {
+		return t.I(`handler`).(*gorest.Handler).Routes()
+	}
+
+	type route struct {
+		Method    string
+		Path      string
+		Operation gorest.Operation
+	}
+
+	var simplify = func(routes []gorest.Route) []route {
+		var rs []route
+		for _, r := range routes {
+			rs = append(rs, route{Method: r.Method, Path: r.Path, Operation: r.Operation})
+		}
+		return rs
+	}
+
+	s.When(`the handler has every standard operation`, func(s *testcase.Spec) {
+		s.Let(`handler`, func(t *testcase.T) interface{} { return gorest.NewHandler(StubController{}) })
+
+		s.Then(`it will list them with the controller type`, func(t *testcase.T) {
+			routes := subject(t)
+			require.Equal(t, []route{
+				{Method: http.MethodGet, Path: `/`, Operation: gorest.OpList},
+				{Method: http.MethodHead, Path: `/`, Operation: gorest.OpList},
+				{Method: http.MethodPost, Path: `/`, Operation: gorest.OpCreate},
+				{Method: http.MethodDelete, Path: `/{id}`, Operation: gorest.OpDelete},
+				{Method: http.MethodGet, Path: `/{id}`, Operation: gorest.OpShow},
+				{Method: http.MethodHead, Path: `/{id}`, Operation: gorest.OpShow},
+				{Method: http.MethodPatch, Path: `/{id}`, Operation: gorest.OpUpdate},
+				{Method: http.MethodPut, Path: `/{id}`, Operation: gorest.OpUpdate},
+			}, simplify(routes))
+
+			for _, r := range routes {
+				require.Equal(t, reflect.TypeOf(StubController{}), r.ControllerType)
+			}
+			require.Equal(t, []string{`id`}, routes[len(routes)-1].PathParams)
+		})
+	})
+
+	s.When(`the handler has nested handlers and custom operations`, func(s *testcase.Spec) {
+		s.Let(`handler`, func(t *testcase.T) interface{} {
+			h := gorest.NewHandler(gorest.AsShowController(http.NotFoundHandler()))
+			perms := gorest.NewHandler(gorest.AsListController(http.NotFoundHandler()))
+			orgs := gorest.NewHandler(gorest.AsShowController(http.NotFoundHandler()))
+			gorest.Mount(orgs, `/permissions`, perms)
+			gorest.Mount(h, `/organizations/`, orgs)
+			h.Handle(`/archive`, http.NotFoundHandler())
+			return h
+		})
+
+		s.Then(`it will walk the nested handlers recursively`, func(t *testcase.T) {
+			routes := subject(t)
+			require.Equal(t, []route{
+				{Method: http.MethodGet, Path: `/{id}`, Operation: gorest.OpShow},
+				{Method: http.MethodHead, Path: `/{id}`, Operation: gorest.OpShow},
+				{Method: http.MethodGet, Path: `/{id}/organizations/{organizations-id}`, Operation: gorest.OpShow},
+				{Method: http.MethodHead, Path: `/{id}/organizations/{organizations-id}`, Operation: gorest.OpShow},
+				{Method: http.MethodGet, Path: `/{id}/organizations/{organizations-id}/permissions`, Operation: gorest.OpList},
+				{Method: http.MethodHead, Path: `/{id}/organizations/{organizations-id}/permissions`, Operation: gorest.OpList},
+				{Method: ``, Path: `/{id}/archive`, Operation: gorest.OpCustom},
+			}, simplify(routes))
+
+			require.Equal(t, []string{`id`, `organizations-id`}, routes[4].PathParams)
+		})
+	})
+}
+
+func TestWalkRoutes(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Test(`resource tree`, func(t *testcase.T) {
+		users := gorest.Resource(`users`, gorest.AsShowController(http.NotFoundHandler()))
+		users.Sub(`contacts`, gorest.AsCreateController(http.NotFoundHandler()))
+		h, err := users.Build()
+		require.Nil(t, err)
+
+		var paths []string
+		require.Nil(t, gorest.WalkRoutes(h, func(r gorest.Route) error {
+			paths = append(paths, r.Method+` `+r.Path)
+			return nil
+		}))
+		require.Equal(t, []string{
+			`GET /users/{users-id}`,
+			`HEAD /users/{users-id}`,
+			`POST /users/{users-id}/contacts`,
+		}, paths)
+	})
+
+	s.Test(`error stops the walk`, func(t *testcase.T) {
+		expected := errors.New(`boom`)
+		var count int
+		err := gorest.WalkRoutes(gorest.NewHandler(StubController{}), func(r gorest.Route) error {
+			count++
+			return expected
+		})
+		require.Equal(t, expected, err)
+		require.Equal(t, 1, count)
+	})
+
+	s.Test(`unknown http.Handler has no routes`, func(t *testcase.T) {
+		require.Nil(t, gorest.WalkRoutes(http.NewServeMux(), func(r gorest.Route) error {
+			return errors.New(`unexpected`)
+		}))
+	})
+
+	s.Test(`operation name`, func(t *testcase.T) {
+		require.Equal(t, `Create|Update`, (gorest.OpCreate | gorest.OpUpdate).String())
+	})
+}
@@ -24,3 +24,12 @@ func Unshift(path string) (id string, remainingPath string) {
 	}
 	return pathParam, newPath
 }
+
+// splitCustomMethod splits a path segment like 42:archive into the resource id and the custom method verb.
+func splitCustomMethod(segment string) (id, verb string, ok bool) {
+	i := strings.LastIndex(segment, `:`)
+	if i <= 0 || i == len(segment)-1 {
+		return segment, ``, false
+	}
+	return segment[:i], segment[i+1:], true
+}
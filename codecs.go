@@ -0,0 +1,267 @@
+package gorest
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONCodec encodes and decodes values with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) MediaType() string { return `application/json` }
+
+func (JSONCodec) Encode(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) }
+
+func (JSONCodec) Decode(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) }
+
+// XMLCodec encodes and decodes values with encoding/xml.
+type XMLCodec struct{}
+
+func (XMLCodec) MediaType() string { return `application/xml` }
+
+// Encode writes v as a single XML document.
+// Slices and values that encoding/xml can't represent, like maps, are rejected with ErrNotAcceptable.
+func (XMLCodec) Encode(w io.Writer, v interface{}) error {
+	if _, ok := v.(xml.Marshaler); !ok {
+		switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+		case reflect.Slice, reflect.Array:
+			return fmt.Errorf(`%w: application/xml can't represent %T as a single document`, ErrNotAcceptable, v)
+		}
+	}
+	err := xml.NewEncoder(w).Encode(v)
+	var ute *xml.UnsupportedTypeError
+	if errors.As(err, &ute) {
+		return fmt.Errorf(`%w: %v`, ErrNotAcceptable, err)
+	}
+	return err
+}
+
+func (XMLCodec) Decode(r io.Reader, v interface{}) error { return xml.NewDecoder(r).Decode(v) }
+
+// TextCodec encodes values as plain text.
+// It can encode strings, []byte, scalars, encoding.TextMarshaler and fmt.Stringer values,
+// and the other values are rejected with ErrNotAcceptable.
+// It can decode into *string, *[]byte and encoding.TextUnmarshaler values.
+type TextCodec struct{}
+
+func (TextCodec) MediaType() string { return `text/plain` }
+
+func (TextCodec) Encode(w io.Writer, v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		_, err := io.WriteString(w, v)
+		return err
+	case []byte:
+		_, err := w.Write(v)
+		return err
+	case encoding.TextMarshaler:
+		bs, err := v.MarshalText()
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bs)
+		return err
+	case fmt.Stringer:
+		_, err := io.WriteString(w, v.String())
+		return err
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		_, err := fmt.Fprint(w, v)
+		return err
+	default:
+		return fmt.Errorf(`%w: text/plain can't represent %T`, ErrNotAcceptable, v)
+	}
+}
+
+func (TextCodec) Decode(r io.Reader, v interface{}) error {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case *string:
+		*v = string(bs)
+		return nil
+	case *[]byte:
+		*v = bs
+		return nil
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(bs)
+	default:
+		return fmt.Errorf(`gorest: text/plain can't be decoded into %T`, v)
+	}
+}
+
+// FormCodec encodes and decodes application/x-www-form-urlencoded values.
+// It supports url.Values, map[string]string, map[string][]string and structs.
+// Struct fields are mapped by their `form` tag, or by their name when the tag is missing.
+type FormCodec struct{}
+
+func (FormCodec) MediaType() string { return `application/x-www-form-urlencoded` }
+
+func (FormCodec) Encode(w io.Writer, v interface{}) error {
+	values, err := formValuesOf(v)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, values.Encode())
+	return err
+}
+
+func (FormCodec) Decode(r io.Reader, v interface{}) error {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(bs))
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case *url.Values:
+		*v = values
+		return nil
+	case *map[string][]string:
+		*v = values
+		return nil
+	case *map[string]string:
+		*v = make(map[string]string, len(values))
+		for key := range values {
+			(*v)[key] = values.Get(key)
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf(`gorest: form values can't be decoded into %T`, v)
+	}
+	return eachFormField(rv.Elem(), func(name string, field reflect.Value) error {
+		vs, ok := values[name]
+		if !ok {
+			return nil
+		}
+		if field.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(field.Type(), len(vs), len(vs))
+			for i, raw := range vs {
+				if err := setFormValue(slice.Index(i), raw); err != nil {
+					return fmt.Errorf(`gorest: form field %s: %w`, name, err)
+				}
+			}
+			field.Set(slice)
+			return nil
+		}
+		if err := setFormValue(field, vs[0]); err != nil {
+			return fmt.Errorf(`gorest: form field %s: %w`, name, err)
+		}
+		return nil
+	})
+}
+
+func formValuesOf(v interface{}) (url.Values, error) {
+	switch v := v.(type) {
+	case url.Values:
+		return v, nil
+	case map[string][]string:
+		return v, nil
+	case map[string]string:
+		values := make(url.Values, len(v))
+		for key, value := range v {
+			values.Set(key, value)
+		}
+		return values, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf(`%w: %T can't be encoded as form values`, ErrNotAcceptable, v)
+	}
+	values := make(url.Values)
+	return values, eachFormField(rv, func(name string, field reflect.Value) error {
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < field.Len(); i++ {
+				values.Add(name, fmt.Sprint(field.Index(i).Interface()))
+			}
+			return nil
+		}
+		values.Set(name, fmt.Sprint(field.Interface()))
+		return nil
+	})
+}
+
+func eachFormField(rv reflect.Value, fn func(name string, field reflect.Value) error) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != `` { // unexported
+			continue
+		}
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup(`form`); ok {
+			if tag == `-` {
+				continue
+			}
+			if n := strings.Split(tag, `,`)[0]; n != `` {
+				name = n
+			}
+		}
+		if err := fn(name, rv.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setFormValue(field reflect.Value, raw string) error {
+	if tu, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(raw))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf(`unsupported field type %s`, field.Type())
+	}
+	return nil
+}
@@ -49,6 +49,28 @@ func AsUpdateController(i interface{}) UpdateController {
 	}
 }
 
+func AsReplaceController(i interface{}) ReplaceController {
+	switch i := i.(type) {
+	case http.Handler:
+		return httpHandlerAsReplaceController{Handler: i}
+	case http.HandlerFunc:
+		return httpHandlerAsReplaceController{Handler: i}
+	default:
+		panic(fmt.Sprintf(`unknown type: %T`, i))
+	}
+}
+
+func AsPatchController(i interface{}) PatchController {
+	switch i := i.(type) {
+	case http.Handler:
+		return httpHandlerAsPatchController{Handler: i}
+	case http.HandlerFunc:
+		return httpHandlerAsPatchController{Handler: i}
+	default:
+		panic(fmt.Sprintf(`unknown type: %T`, i))
+	}
+}
+
 func AsDeleteController(i interface{}) DeleteController {
 	switch i := i.(type) {
 	case http.Handler:
@@ -86,6 +108,18 @@ func (ctrl httpHandlerAsUpdateController) Update(w http.ResponseWriter, r *http.
 	ctrl.Handler.ServeHTTP(w, r)
 }
 
+type httpHandlerAsReplaceController struct{ http.Handler }
+
+func (ctrl httpHandlerAsReplaceController) Replace(w http.ResponseWriter, r *http.Request) {
+	ctrl.Handler.ServeHTTP(w, r)
+}
+
+type httpHandlerAsPatchController struct{ http.Handler }
+
+func (ctrl httpHandlerAsPatchController) Patch(w http.ResponseWriter, r *http.Request) {
+	ctrl.Handler.ServeHTTP(w, r)
+}
+
 type httpHandlerAsDeleteController struct{ http.Handler }
 
 func (ctrl httpHandlerAsDeleteController) Delete(w http.ResponseWriter, r *http.Request) {
@@ -33,3 +33,12 @@ func (ctrl MyController) Show(w http.ResponseWriter, r *http.Request) {}
 func (ctrl MyController) Update(w http.ResponseWriter, r *http.Request) {}
 
 func (ctrl MyController) Delete(w http.ResponseWriter, r *http.Request) {}
+
+func ExampleResourceController() {
+	var repository gorest.Repository[Teapot, string] // your repository implementation
+
+	mux := http.NewServeMux()
+	gorest.Mount(mux, `/teapots`, gorest.NewHandler(gorest.ResourceController[Teapot, string]{
+		Repository: repository,
+	}))
+}
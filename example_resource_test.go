@@ -0,0 +1,23 @@
+package gorest_test
+
+import (
+	"net/http"
+
+	"github.com/adamluzsi/gorest"
+)
+
+func ExampleResource() {
+	resources := gorest.Resource(`resources`, ResourceController{})
+	resources.Sub(`sub-resources`, SubResourceController{})
+
+	handler, err := resources.Build()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(`/`, handler)
+
+	// this will cause http.ServeMux to have handlers by the controller structures in hierarchy:
+	//	GET /resources/{resourceID}/sub-resources/{sub-resourceID}
+}
@@ -1,9 +1,18 @@
 module github.com/adamluzsi/gorest
 
-go 1.13
+go 1.18
 
 require (
 	github.com/adamluzsi/frameless v0.4.0
 	github.com/adamluzsi/testcase v0.5.1
 	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v2 v2.2.8
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/mock v1.4.3 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/tools v0.0.0-20200416061724-5744cfde56ed // indirect
+	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
 )
@@ -0,0 +1,48 @@
+package gorest
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// headHandler serves HEAD requests with a GET operation.
+// The response body is discarded, while the headers and the Content-Length are kept.
+type headHandler struct{ http.Handler }
+
+func (h headHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hw := &headResponseWriter{ResponseWriter: w}
+	h.Handler.ServeHTTP(hw, r)
+	hw.finish()
+}
+
+type headResponseWriter struct {
+	http.ResponseWriter
+	code   int
+	length int
+}
+
+func (w *headResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *headResponseWriter) Write(bs []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.length += len(bs)
+	return len(bs), nil
+}
+
+// finish writes the headers of the response.
+// The header write is delayed until the end, so the Content-Length of the discarded body can be calculated.
+func (w *headResponseWriter) finish() {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.Header().Get(`Content-Length`) == `` && 0 < w.length {
+		w.Header().Set(`Content-Length`, strconv.Itoa(w.length))
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
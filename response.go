@@ -0,0 +1,54 @@
+package gorest
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// trackingResponseWriter records whether the response headers were already sent,
+// so a recovered panic knows if an error response can still be written.
+type trackingResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func newTrackingResponseWriter(w http.ResponseWriter) *trackingResponseWriter {
+	if tw, ok := w.(*trackingResponseWriter); ok {
+		return tw
+	}
+	return &trackingResponseWriter{ResponseWriter: w}
+}
+
+func (w *trackingResponseWriter) WriteHeader(code int) {
+	if code >= http.StatusOK { // informational responses can be followed by the final response
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *trackingResponseWriter) Write(bs []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(bs)
+}
+
+func (w *trackingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+func (w *trackingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.wroteHeader = true
+	return h.Hijack()
+}
+
+// Unwrap returns the original http.ResponseWriter for http.ResponseController.
+func (w *trackingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -50,6 +50,14 @@ func NewInternalServerErrorHandler(ctrl interface {
 	return http.HandlerFunc(ctrl.InternalServerError)
 }
 
+type ErrorHandlerController struct {
+	Code int
+}
+
+func (h ErrorHandlerController) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, err.Error(), h.Code)
+}
+
 type ErrorContextHandler struct {
 	Err error
 }
@@ -114,6 +122,14 @@ func (s StubController) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type HeadControllerFunc func(w http.ResponseWriter, r *http.Request)
+
+func (fn HeadControllerFunc) Head(w http.ResponseWriter, r *http.Request) { fn(w, r) }
+
+type ListHeadControllerFunc func(w http.ResponseWriter, r *http.Request)
+
+func (fn ListHeadControllerFunc) ListHead(w http.ResponseWriter, r *http.Request) { fn(w, r) }
+
 type TestController struct{}
 
 type ContextTestIDKey struct{}
@@ -146,6 +162,10 @@ func (d TestController) NotFound(w http.ResponseWriter, r *http.Request) {
 	_, _ = fmt.Fprintf(w, `not-found`)
 }
 
+func (d TestController) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	_, _ = fmt.Fprintf(w, `method-not-allowed`)
+}
+
 func (d TestController) InternalServerError(w http.ResponseWriter, r *http.Request) {
 	_, _ = fmt.Fprintf(w, `internal-server-error`)
 }
@@ -160,6 +180,7 @@ var _ interface {
 	gorest.DeleteController
 
 	gorest.WithNotFoundHandler
+	gorest.WithMethodNotAllowedHandler
 	gorest.WithInternalServerErrorHandler
 } = TestController{}
 